@@ -0,0 +1,22 @@
+#version 330
+
+const vec3 lightPos = vec3(-2, 2, -1);
+
+in vec4 color0;
+in vec3 normal0;
+in vec3 position0;
+
+layout(location=0) out vec4 out_diffuse;
+layout(location=1) out vec4 out_normal;
+layout(location=2) out vec4 out_position;
+
+void main() {
+    // translucent faces are drawn after the light pass, so apply a simple directional light
+    vec3 dir = normalize(lightPos);
+    float contrib = max(dot(dir, normal0), 0.0);
+    out_diffuse = vec4((0.5 + 0.5 * contrib) * color0.rgb, color0.a);
+
+    // zero alpha leaves the gbuffer normal & position of the geometry behind untouched
+    out_normal = vec4((normal0 + 1.0) / 2.0, 0);
+    out_position = vec4(position0, 0);
+}
@@ -6,8 +6,9 @@ uniform mat4 projection;
 
 in vec3 position;
 in int normal_id;
-in vec3 color;
+in vec4 color;
 in float occlusion;
+in vec3 light;
 
 out vec4 color0;
 out vec3 normal0;
@@ -36,8 +37,9 @@ void main() {
     // gbuffer view space position
     position0 = (mv * vec4(position, 1.0)).xyz;
 
-    // pass color and occlusion
-    color0 = vec4(color * (1 - occlusion), 1);
+    // pass color, occlusion and block light
+    vec3 brightness = min(vec3(1), vec3(1 - occlusion) + light);
+    color0 = vec4(color.rgb * brightness, 1);
 
     // finally, transform view -> clip space and output vertex position
     gl_Position = projection * vec4(position0, 1);
@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/johanhenriksson/goworld/engine"
+	"github.com/johanhenriksson/goworld/math/vec2"
+	"github.com/johanhenriksson/goworld/ui"
+)
+
+// DebugCullWindow shows how many components were frustum culled or occluded in each render pass in the last frame
+func DebugCullWindow(app *engine.Application) ui.Component {
+	pipeline := app.Pipeline
+	window := ui.NewRect(WindowStyle,
+		ui.NewText("Culling", ui.NoStyle),
+		newCullStatsText("Geometry", func() engine.CullStats { return pipeline.Geometry.Stats }),
+		newCullStatsText("Shadows", func() engine.CullStats { return pipeline.Light.Shadows.Stats }),
+		newCullStatsText("Forward", func() engine.CullStats { return pipeline.Forward.Stats }),
+		newCullStatsText("Lines", func() engine.CullStats { return pipeline.Lines.Stats }))
+	window.SetPosition(vec2.New(270, 10))
+	window.Flow(vec2.New(500, 200))
+	return window
+}
+
+// cullStatsText displays the culling statistics of a render pass, updated every frame
+type cullStatsText struct {
+	*ui.Text
+	name  string
+	stats func() engine.CullStats
+}
+
+func newCullStatsText(name string, stats func() engine.CullStats) *cullStatsText {
+	return &cullStatsText{
+		Text:  ui.NewText(formatCullStats(name, engine.CullStats{}), ui.NoStyle),
+		name:  name,
+		stats: stats,
+	}
+}
+
+func (t *cullStatsText) Draw(args engine.DrawArgs) {
+	t.Set(formatCullStats(t.name, t.stats()))
+	t.Text.Draw(args)
+}
+
+func formatCullStats(name string, stats engine.CullStats) string {
+	return fmt.Sprintf("%-8s %5d / %5d culled %5d occluded", name, stats.Culled, stats.Tested, stats.Occluded)
+}
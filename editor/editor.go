@@ -16,6 +16,7 @@ import (
 type Editor struct {
 	*object.T
 
+	World   *game.World
 	Chunk   *game.Chunk
 	Camera  *engine.Camera
 	Palette *PaletteWindow
@@ -26,33 +27,37 @@ type Editor struct {
 	SampleTool  *SampleTool
 	ReplaceTool *ReplaceTool
 
+	// Block is the block type placed by the editor tools
+	Block game.BlockID
+
 	XPlane *plane.T
 	YPlane *plane.T
 	ZPlane *plane.T
 
 	xp, yp, zp int
 
-	bounds  *box.T
-	mesh    *game.ChunkMesh
-	gbuffer *render.GeometryBuffer
+	bounds *box.T
+	mesh   *game.ChunkMesh
 }
 
 // NewEditor creates a new editor application
-func NewEditor(chunk *game.Chunk, camera *engine.Camera, gbuffer *render.GeometryBuffer) *Editor {
+func NewEditor(world *game.World, chunk *game.Chunk, camera *engine.Camera) *Editor {
 	e := &Editor{
 		T:       object.New("Editor"),
+		World:   world,
 		Chunk:   chunk,
 		Camera:  camera,
 		Palette: NewPaletteWindow(render.DefaultPalette),
+		Block:   game.ColorBlock,
 
 		PlaceTool:   NewPlaceTool(),
 		EraseTool:   NewEraseTool(),
 		SampleTool:  NewSampleTool(),
 		ReplaceTool: NewReplaceTool(),
 
-		mesh:    game.NewChunkMesh(chunk),
-		gbuffer: gbuffer,
+		mesh: game.NewChunkMesh(chunk),
 	}
+	e.Palette.SetBlock(game.Block(e.Block))
 
 	dimensions := vec3.NewI(chunk.Sx, chunk.Sy, chunk.Sz)
 	center := dimensions.Scaled(0.5)
@@ -91,6 +96,10 @@ func NewEditor(chunk *game.Chunk, camera *engine.Camera, gbuffer *render.Geometr
 		Active(false).
 		Create(e.T)
 
+	// mesh chunk borders against loaded neighbors, and remesh when they change
+	e.mesh.Source = world
+	world.Subscribe(e.onChunkEvent)
+
 	e.SelectTool(e.PlaceTool)
 
 	// could we avoid this somehow?
@@ -120,11 +129,57 @@ func (e *Editor) Update(dt float32) {
 	e.updateConstructPlanes()
 	e.updateTool()
 
+	// cycle block types
+	if keys.Pressed(keys.B) {
+		e.NextBlock()
+	}
+
 	// clear chunk
 	if keys.Pressed(keys.N) && keys.Ctrl() {
 		e.Chunk.Clear()
-		e.Chunk.Light.Calculate()
-		e.mesh.Compute()
+		e.World.Touch(e.Chunk)
+	}
+}
+
+// NextBlock selects the next block type in the block registry, skipping air
+func (e *Editor) NextBlock() {
+	types := game.BlockTypes()
+	for i, block := range types {
+		if block.ID == e.Block {
+			e.Block = types[(i+1)%len(types)].ID
+			break
+		}
+	}
+	if e.Block == game.AirBlock {
+		e.NextBlock()
+		return
+	}
+	e.Palette.SetBlock(game.Block(e.Block))
+}
+
+// SelectedVoxel returns the voxel placed by the editor tools
+func (e *Editor) SelectedVoxel() game.Voxel {
+	return game.NewBlockVoxel(e.Block, e.Palette.Selected)
+}
+
+// SetVoxel replaces the voxel at a position within the edited chunk. The world takes care of
+// updating the light, remeshing and saving. Positions outside the chunk are ignored.
+func (e *Editor) SetVoxel(position vec3.T, voxel game.Voxel) {
+	x, y, z := int(position.X), int(position.Y), int(position.Z)
+	if !e.Chunk.Contains(x, y, z) || e.Chunk.At(x, y, z).Type().Indestructible {
+		return
+	}
+	e.World.Set(e.Chunk.Ox+x, e.Chunk.Oy+y, e.Chunk.Oz+z, voxel)
+}
+
+// onChunkEvent remeshes the edited chunk when it or one of its neighbors changes
+func (e *Editor) onChunkEvent(event game.ChunkEvent) {
+	self := game.ChunkPos{X: e.Chunk.Cx, Y: e.Chunk.Cy, Z: e.Chunk.Cz}
+	for _, cp := range event.Touched {
+		if cp == self {
+			e.mesh.Compute()
+			return
+		}
 	}
 }
 
@@ -210,25 +265,16 @@ func (e *Editor) updateTool() {
 	}
 }
 
-// sample world position at current mouse coords
+// cursorPositionNormal casts a ray from the mouse cursor into the edited chunk, and returns the
+// point where it hits the surface of a voxel along with the normal of the surface
 func (e *Editor) cursorPositionNormal() (bool, vec3.T, vec3.T) {
-	depth, depthExists := e.gbuffer.SampleDepth(mouse.Position)
-	if !depthExists {
-		return false, vec3.Zero, vec3.Zero
-	}
+	ray := e.Camera.ScreenRay(mouse.Position)
+	ray.Origin = ray.Origin.Sub(vec3.NewI(e.Chunk.Ox, e.Chunk.Oy, e.Chunk.Oz))
 
-	viewNormal, normalExists := e.gbuffer.SampleNormal(mouse.Position)
-	if !normalExists {
+	hit, exists := e.Chunk.Raycast(ray, e.Camera.Far)
+	if !exists {
 		return false, vec3.Zero, vec3.Zero
 	}
 
-	position := e.Camera.Unproject(vec3.Extend(
-		mouse.Position.Div(e.gbuffer.Depth.Size()),
-		depth,
-	))
-
-	viewInv := e.Camera.View.Invert()
-	normal := viewInv.TransformDir(viewNormal)
-
-	return true, position, normal
+	return true, hit.Point(ray), hit.Normal
 }
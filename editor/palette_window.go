@@ -1,7 +1,10 @@
 package editor
 
 import (
+	"fmt"
+
 	"github.com/johanhenriksson/goworld/engine/mouse"
+	"github.com/johanhenriksson/goworld/game"
 	"github.com/johanhenriksson/goworld/math/vec2"
 	"github.com/johanhenriksson/goworld/render"
 	"github.com/johanhenriksson/goworld/ui"
@@ -11,6 +14,8 @@ type PaletteWindow struct {
 	*ui.Rect
 	Palette  render.Palette
 	Selected render.Color
+
+	block *ui.Text
 }
 
 func NewPaletteWindow(palette render.Palette) *PaletteWindow {
@@ -23,6 +28,7 @@ func NewPaletteWindow(palette render.Palette) *PaletteWindow {
 	wnd := &PaletteWindow{
 		Palette:  palette,
 		Selected: palette[0],
+		block:    ui.NewText(formatBlock(game.Block(game.ColorBlock)), ui.NoStyle),
 	}
 
 	for i := 1; i <= len(palette); i++ {
@@ -47,9 +53,20 @@ func NewPaletteWindow(palette render.Palette) *PaletteWindow {
 
 	wnd.Rect = ui.NewRect(WindowStyle,
 		ui.NewText("Palette", ui.NoStyle),
-		ui.NewRect(gridStyle, rows...))
+		ui.NewRect(gridStyle, rows...),
+		wnd.block)
 
 	wnd.Flow(vec2.New(200, 400))
 
 	return wnd
 }
+
+// SetBlock shows the block type placed with the selected color
+func (w *PaletteWindow) SetBlock(block *game.BlockType) {
+	w.block.Set(formatBlock(block))
+}
+
+// formatBlock pads the block name, so that the text keeps its size as the block changes
+func formatBlock(block *game.BlockType) string {
+	return fmt.Sprintf("Block: %-8s", block.Name)
+}
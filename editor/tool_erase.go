@@ -28,14 +28,7 @@ func (pt *EraseTool) String() string {
 
 func (pt *EraseTool) Use(e *Editor, position, normal vec3.T) {
 	target := position.Sub(normal.Scaled(0.5))
-	e.Chunk.Set(int(target.X), int(target.Y), int(target.Z), game.EmptyVoxel)
-
-	// recompute mesh
-	e.Chunk.Light.Calculate()
-	e.mesh.Compute()
-
-	// write to disk
-	go e.Chunk.Write("chunks")
+	e.SetVoxel(target, game.EmptyVoxel)
 }
 
 func (pt *EraseTool) Hover(editor *Editor, position, normal vec3.T) {
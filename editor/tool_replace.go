@@ -2,7 +2,6 @@ package editor
 
 import (
 	"github.com/johanhenriksson/goworld/engine/object"
-	"github.com/johanhenriksson/goworld/game"
 	"github.com/johanhenriksson/goworld/geometry/box"
 	"github.com/johanhenriksson/goworld/math/vec3"
 	"github.com/johanhenriksson/goworld/render"
@@ -28,14 +27,7 @@ func (pt *ReplaceTool) String() string {
 
 func (pt *ReplaceTool) Use(e *Editor, position, normal vec3.T) {
 	target := position.Sub(normal.Scaled(0.5))
-	e.Chunk.Set(int(target.X), int(target.Y), int(target.Z), game.NewVoxel(e.Palette.Selected))
-
-	// recompute mesh
-	e.Chunk.Light.Calculate()
-	e.mesh.Compute()
-
-	// write to disk
-	go e.Chunk.Write("chunks")
+	e.SetVoxel(target, e.SelectedVoxel())
 }
 
 func (pt *ReplaceTool) Hover(editor *Editor, position, normal vec3.T) {
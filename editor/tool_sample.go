@@ -2,6 +2,7 @@ package editor
 
 import (
 	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/game"
 	"github.com/johanhenriksson/goworld/geometry/box"
 	"github.com/johanhenriksson/goworld/math/vec3"
 	"github.com/johanhenriksson/goworld/render"
@@ -29,6 +30,9 @@ func (pt *SampleTool) Use(e *Editor, position, normal vec3.T) {
 	target := position.Sub(normal.Scaled(0.5))
 	voxel := e.Chunk.At(int(target.X), int(target.Y), int(target.Z))
 	e.Palette.Selected = render.Color4(float32(voxel.R)/255, float32(voxel.G)/255, float32(voxel.B)/255, 1)
+	if voxel.Block != game.AirBlock {
+		e.Block = voxel.Block
+	}
 
 	// select placement tool
 	e.SelectTool(e.PlaceTool)
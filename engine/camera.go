@@ -7,6 +7,7 @@ import (
 	"github.com/johanhenriksson/goworld/math/mat4"
 	"github.com/johanhenriksson/goworld/math/vec2"
 	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
 	"github.com/johanhenriksson/goworld/render"
 )
 
@@ -54,6 +55,22 @@ func (cam *Camera) Unproject(pos vec3.T) vec3.T {
 	return pvi.TransformPoint(point)
 }
 
+// ScreenRay returns a ray from the near plane through the given screen position, in pixels
+func (cam *Camera) ScreenRay(pos vec2.T) physics.Ray {
+	screen := pos.Div(vec2.NewI(cam.Buffer.Width, cam.Buffer.Height))
+	near := cam.Unproject(vec3.Extend(screen, 0))
+	far := cam.Unproject(vec3.Extend(screen, 1))
+	return physics.Ray{
+		Origin: near,
+		Dir:    far.Sub(near).Normalized(),
+	}
+}
+
+// Frustum returns the view frustum of the camera in world space
+func (cam *Camera) Frustum() physics.Frustum {
+	return physics.NewFrustum(cam.Projection.Mul(&cam.View))
+}
+
 // Update the camera
 func (cam *Camera) Update(dt float32) {
 	/* Mouse look */
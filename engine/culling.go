@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/math/mat4"
+	"github.com/johanhenriksson/goworld/physics"
+)
+
+// Bounded components have a bounding box. Draw passes skip bounded components whose
+// bounds are entirely outside of the view frustum.
+type Bounded interface {
+	// Bounds returns the bounding box of the component in object space,
+	// or false if the bounds are not known.
+	Bounds() (physics.Box, bool)
+}
+
+// Occludable components can be hidden from the camera by occlusion culling
+type Occludable interface {
+	// Occluded returns true if the component is known to be hidden behind something else
+	Occluded() bool
+}
+
+// CullStats counts the components tested against the view frustum during a draw pass,
+// and the components inside the frustum that were skipped because they were occluded
+type CullStats struct {
+	Tested   int
+	Culled   int
+	Occluded int
+}
+
+// Add returns the sum of two sets of statistics
+func (s CullStats) Add(other CullStats) CullStats {
+	return CullStats{
+		Tested:   s.Tested + other.Tested,
+		Culled:   s.Culled + other.Culled,
+		Occluded: s.Occluded + other.Occluded,
+	}
+}
+
+// Visible returns true if a component transformed to world space by the given matrix may be
+// visible through the frustum. Components without bounds are always visible and are not counted.
+func (s *CullStats) Visible(frustum *physics.Frustum, component object.Component, transform mat4.T) bool {
+	bounded, ok := component.(Bounded)
+	if !ok {
+		return true
+	}
+	bounds, ok := bounded.Bounds()
+	if !ok {
+		return true
+	}
+	s.Tested++
+	if frustum.IntersectsBox(bounds.Transform(transform)) {
+		return true
+	}
+	s.Culled++
+	return false
+}
+
+// Unoccluded returns false if the component is occluded. Occluded components are counted.
+func (s *CullStats) Unoccluded(component object.Component) bool {
+	if occludable, ok := component.(Occludable); ok && occludable.Occluded() {
+		s.Occluded++
+		return false
+	}
+	return true
+}
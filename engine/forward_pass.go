@@ -1,8 +1,12 @@
 package engine
 
 import (
+	"sort"
+
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
 	"github.com/johanhenriksson/goworld/render"
 )
 
@@ -10,8 +14,18 @@ type ForwardDrawable interface {
 	DrawForward(DrawArgs)
 }
 
+// DepthSorted drawables are blended with whatever is behind them. They are drawn after all other
+// forward drawables, back to front by the distance from the camera to their center point.
+type DepthSorted interface {
+	// Center returns the center point of the drawable in object space
+	Center() vec3.T
+}
+
 // ForwardPass holds information required to perform a forward rendering pass.
 type ForwardPass struct {
+	// Stats holds the frustum culling statistics of the last frame
+	Stats CullStats
+
 	output  *render.ColorBuffer
 	gbuffer *render.GeometryBuffer
 	fbo     *render.FrameBuffer
@@ -43,15 +57,6 @@ func (p *ForwardPass) Draw(scene *Scene) {
 	render.BlendMultiply()
 	render.CullFace(render.CullBack)
 
-	// todo: depth sorting
-	// there is finally a decent way of doing it!!
-	// now we just need a way to compute the distance from an object to the camera
-	// ... and a way to sort the queue
-
-	// todo: frustum culling
-	// lets not draw stuff thats behind us at the very least
-	// ... things need bounding boxes though.
-
 	p.fbo.Bind()
 	defer p.fbo.Unbind()
 	p.fbo.DrawBuffers()
@@ -67,12 +72,41 @@ func (p *ForwardPass) Draw(scene *Scene) {
 	scene.Collect(&query)
 
 	args := scene.Camera.DrawArgs()
-	for _, component := range query.Results {
+	frustum := physics.NewFrustum(args.VP)
+	p.Stats = CullStats{}
+	for _, component := range sortBackToFront(query.Results, args.Position) {
+		drawArgs := args.Apply(component.Parent().Transform())
+		if !p.Stats.Visible(&frustum, component, drawArgs.Transform) || !p.Stats.Unoccluded(component) {
+			continue
+		}
 		drawable := component.(ForwardDrawable)
-		drawable.DrawForward(args.Apply(component.Parent().Transform()))
+		drawable.DrawForward(drawArgs)
 	}
 
 	render.DepthOutput(true)
 
 	render.CullFace(render.CullNone)
 }
+
+// sortBackToFront orders depth sorted components by decreasing distance to the eye position.
+// Components that are not depth sorted keep their order, and are placed first.
+func sortBackToFront(components []object.Component, eye vec3.T) []object.Component {
+	distance := make(map[object.Component]float32, len(components))
+	for _, component := range components {
+		if sorted, ok := component.(DepthSorted); ok {
+			transform := component.Parent().Transform()
+			center := transform.TransformPoint(sorted.Center())
+			distance[component] = center.Sub(eye).LengthSqr()
+		}
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		di, iSorted := distance[components[i]]
+		dj, jSorted := distance[components[j]]
+		if iSorted != jSorted {
+			return jSorted
+		}
+		return di > dj
+	})
+	return components
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/physics"
 	"github.com/johanhenriksson/goworld/render"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
@@ -14,6 +15,9 @@ type DeferredDrawable interface {
 // GeometryPass draws the scene geometry to a G-buffer
 type GeometryPass struct {
 	Buffer *render.GeometryBuffer
+
+	// Stats holds the frustum culling statistics of the last frame
+	Stats CullStats
 }
 
 // Resize is called on window resize. Should update any window size-dependent buffers
@@ -55,9 +59,15 @@ func (p *GeometryPass) Draw(scene *Scene) {
 	scene.Collect(&query)
 
 	args := scene.Camera.DrawArgs()
+	frustum := physics.NewFrustum(args.VP)
+	p.Stats = CullStats{}
 	for _, component := range query.Results {
+		drawArgs := args.Apply(component.Parent().Transform())
+		if !p.Stats.Visible(&frustum, component, drawArgs.Transform) || !p.Stats.Unoccluded(component) {
+			continue
+		}
 		drawable := component.(DeferredDrawable)
-		drawable.DrawDeferred(args.Apply(component.Parent().Transform()))
+		drawable.DrawDeferred(drawArgs)
 	}
 
 	p.Buffer.Unbind()
@@ -130,6 +130,7 @@ func (p *LightPass) Draw(scene *Scene) {
 	render.DepthOutput(false)
 
 	// draw lights one by one
+	p.Shadows.BeginFrame()
 	for _, light := range scene.Lights {
 		// draw shadow pass for this light into shadow map
 		p.Shadows.DrawLight(scene, &light)
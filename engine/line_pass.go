@@ -1,6 +1,9 @@
 package engine
 
-import "github.com/johanhenriksson/goworld/engine/object"
+import (
+	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/physics"
+)
 
 type LineDrawable interface {
 	DrawLines(DrawArgs)
@@ -8,6 +11,8 @@ type LineDrawable interface {
 
 // LinePass draws line geometry
 type LinePass struct {
+	// Stats holds the frustum culling statistics of the last frame
+	Stats CullStats
 }
 
 // NewLinePass sets up a line geometry pass.
@@ -28,8 +33,14 @@ func (p *LinePass) Draw(scene *Scene) {
 	scene.Collect(&query)
 
 	args := scene.Camera.DrawArgs()
+	frustum := physics.NewFrustum(args.VP)
+	p.Stats = CullStats{}
 	for _, component := range query.Results {
+		drawArgs := args.Apply(component.Parent().Transform())
+		if !p.Stats.Visible(&frustum, component, drawArgs.Transform) {
+			continue
+		}
 		drawable := component.(LineDrawable)
-		drawable.DrawLines(args.Apply(component.Parent().Transform()))
+		drawable.DrawLines(drawArgs)
 	}
 }
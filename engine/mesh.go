@@ -5,6 +5,7 @@ import (
 
 	"github.com/johanhenriksson/goworld/assets"
 	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/physics"
 	"github.com/johanhenriksson/goworld/render"
 )
 
@@ -17,7 +18,8 @@ type Mesh struct {
 	Pass     render.Pass
 	Material *render.Material
 
-	vao *render.VertexArray
+	vao    *render.VertexArray
+	bounds *physics.Box
 }
 
 // NewMesh creates a new mesh object
@@ -47,6 +49,19 @@ func (m *Mesh) SetIndexType(t render.GLType) {
 	m.vao.SetIndexType(t)
 }
 
+// SetBounds sets the bounding box of the mesh in object space, which allows it to be frustum culled
+func (m *Mesh) SetBounds(bounds physics.Box) {
+	m.bounds = &bounds
+}
+
+// Bounds returns the bounding box of the mesh in object space, if it has been set
+func (m *Mesh) Bounds() (physics.Box, bool) {
+	if m.bounds == nil {
+		return physics.Box{}, false
+	}
+	return *m.bounds, true
+}
+
 func (m *Mesh) DrawDeferred(args DrawArgs) {
 	if m.Pass != render.Geometry {
 		return
@@ -93,6 +108,11 @@ func (m *Mesh) DrawLines(args DrawArgs) {
 	m.vao.Draw()
 }
 
+// Delete frees the GPU resources held by the mesh
+func (m *Mesh) Delete() {
+	m.vao.Delete()
+}
+
 func (m Mesh) Buffer(data interface{}) {
 	pointers := m.Material.VertexPointers(data)
 
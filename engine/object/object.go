@@ -88,6 +88,17 @@ func (o *T) Attach(components ...Component) {
 	}
 }
 
+// Detach a child component from this object
+func (o *T) Detach(component Component) {
+	for i, child := range o.components {
+		if child == component {
+			o.components = append(o.components[:i], o.components[i+1:]...)
+			component.SetParent(nil)
+			return
+		}
+	}
+}
+
 // Update this object and its child components
 func (o *T) Update(dt float32) {
 	// o.updateTransform()
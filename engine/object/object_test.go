@@ -20,3 +20,19 @@ func TestObjectTransforms(t *testing.T) {
 		t.Errorf("child transform is wrong, was %f", v.X)
 	}
 }
+
+func TestObjectDetach(t *testing.T) {
+	a := New("A")
+	b := New("B")
+	a.Attach(b)
+	a.Detach(b)
+
+	if b.Parent() != nil {
+		t.Error("detached object should not have a parent")
+	}
+	query := NewQuery(func(c Component) bool { return true })
+	a.Collect(&query)
+	if len(query.Results) != 0 {
+		t.Errorf("expected no children after detach, found %d", len(query.Results))
+	}
+}
@@ -3,8 +3,10 @@ package engine
 import (
 	"github.com/go-gl/gl/v4.1-core/gl"
 
-	// "github.com/johanhenriksson/goworld/math/mat4"
-	// "github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/math/mat4"
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
 	"github.com/johanhenriksson/goworld/render"
 )
 
@@ -14,6 +16,10 @@ type ShadowPass struct {
 	Width  int
 	Height int
 
+	// Stats holds the frustum culling statistics of the shadow casters drawn for all lights
+	// during the last frame
+	Stats CullStats
+
 	shadowmap *render.FrameBuffer
 }
 
@@ -45,6 +51,12 @@ func (p *ShadowPass) Resize(width, height int) {}
 
 func (p *ShadowPass) Draw(scene *Scene) {}
 
+// BeginFrame resets the culling statistics. Called once per frame, before the shadow maps of the
+// frame are drawn with DrawLight.
+func (p *ShadowPass) BeginFrame() {
+	p.Stats = CullStats{}
+}
+
 // DrawLight draws a shadow pass for the given light.
 func (p *ShadowPass) DrawLight(scene *Scene, light *Light) {
 	if !light.Shadows {
@@ -66,24 +78,37 @@ func (p *ShadowPass) DrawLight(scene *Scene, light *Light) {
 
 	// compute world to lightspace (light's view projection) matrix
 	// todo: move to light object
-	// lp := light.Projection
-	// lv := mat4.LookAt(light.Position, vec3.Zero)
-	// lvp := lp.Mul(&lv)
-
-	// draw shadow casters
-	// scene.CollectWithArgs(p, DrawArgs{
-	// 	Projection: lp,
-	// 	View:       lv,
-	// 	VP:         lvp,
-	// 	MVP:        lvp,
-	// 	Transform:  mat4.Ident(),
-	// 	Pass:       render.Geometry,
-	// })
-
-	// for _, cmd := range p.queue.items {
-	// 	drawable := cmd.Component.(DeferredDrawable)
-	// 	drawable.DrawDeferred(cmd.Args)
-	// }
+	lp := light.Projection
+	lv := mat4.LookAt(light.Position, vec3.Zero)
+	lvp := lp.Mul(&lv)
+
+	// draw shadow casters within the light frustum
+	query := object.NewQuery(func(c object.Component) bool {
+		_, ok := c.(DeferredDrawable)
+		return ok
+	})
+	scene.Collect(&query)
+
+	args := DrawArgs{
+		Projection: lp,
+		View:       lv,
+		VP:         lvp,
+		MVP:        lvp,
+		Transform:  mat4.Ident(),
+		Position:   light.Position,
+		Pass:       render.Geometry,
+	}
+	frustum := physics.NewFrustum(lvp)
+	stats := CullStats{}
+	for _, component := range query.Results {
+		drawArgs := args.Apply(component.Parent().Transform())
+		if !stats.Visible(&frustum, component, drawArgs.Transform) {
+			continue
+		}
+		drawable := component.(DeferredDrawable)
+		drawable.DrawDeferred(drawArgs)
+	}
+	p.Stats = p.Stats.Add(stats)
 
 	render.DepthOutput(false)
 }
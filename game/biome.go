@@ -0,0 +1,64 @@
+package game
+
+import (
+	"github.com/johanhenriksson/goworld/math"
+)
+
+// BiomeBlend is the distance in climate space over which neighboring biomes are blended
+const BiomeBlend = 0.15
+
+// Biome describes the terrain of a climate zone. Each column of the world belongs to the biome
+// with the climate closest to the temperature and humidity at the column.
+type Biome struct {
+	Name string
+
+	// Temperature and Humidity is the climate of the biome, in the range [-1, 1]
+	Temperature float32
+	Humidity    float32
+
+	// Surface is the top voxel of each column
+	Surface Voxel
+
+	// Soil fills the SoilDepth voxels below the surface
+	Soil      Voxel
+	SoilDepth int
+
+	// Stone fills the rest of the column
+	Stone Voxel
+
+	// Height is the base terrain height. Hills and Ridges scale the rolling hills and
+	// sharp ridges added on top of it.
+	Height float32
+	Hills  float32
+	Ridges float32
+
+	// Features are placed on the surface of the biome
+	Features []ColumnFeature
+
+	// Structures are larger features, which may span several columns
+	Structures []StructurePlacement
+}
+
+// ColumnFeature is a stack of voxels placed on top of the surface of a column,
+// such as a cactus or a shrub
+type ColumnFeature struct {
+	Voxel Voxel
+
+	// Chance is the probability of the feature appearing on a column
+	Chance float32
+
+	// MinHeight and MaxHeight limit the height of the stack
+	MinHeight int
+	MaxHeight int
+}
+
+// String returns the name of the biome
+func (b *Biome) String() string {
+	return b.Name
+}
+
+// climateDistance returns the distance from the biome to the given climate
+func (b *Biome) climateDistance(temperature, humidity float32) float32 {
+	dt, dh := b.Temperature-temperature, b.Humidity-humidity
+	return math.Sqrt(dt*dt + dh*dh)
+}
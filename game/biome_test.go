@@ -0,0 +1,85 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestBiomeAt(t *testing.T) {
+	wg := testWorldgen(t, 31481234, 16)
+	found := map[string]bool{}
+	for x := -4000; x <= 4000; x += 50 {
+		for z := -4000; z <= 4000; z += 50 {
+			biome := wg.BiomeAt(x, z)
+			if biome != wg.BiomeAt(x, z) {
+				t.Fatal("expected biomes to be deterministic")
+			}
+
+			// the selected biome is the one closest to the local climate
+			temperature, humidity := wg.climate(float32(x), float32(z))
+			for _, other := range wg.Biomes {
+				if other.climateDistance(temperature, humidity) < biome.climateDistance(temperature, humidity) {
+					t.Fatalf("expected %s to be closer than %s at %d,%d", biome, other, x, z)
+				}
+			}
+			found[biome.Name] = true
+		}
+	}
+	for _, biome := range wg.Biomes {
+		if !found[biome.Name] {
+			t.Errorf("expected to find %s", biome)
+		}
+	}
+
+	world := testWorld(newMemoryStore())
+	if world.BiomeAt(0, 0) != nil {
+		t.Error("expected no biomes from a provider without them")
+	}
+	world.Provider = wg
+	if world.BiomeAt(10, 20) != wg.BiomeAt(10, 20) {
+		t.Error("expected the world to report biomes of its provider")
+	}
+}
+
+func TestBiomeBordersBlend(t *testing.T) {
+	wg := testWorldgen(t, 31481234, 16)
+	borders := 0
+	for z := -2000; z <= 2000; z += 400 {
+		prev := wg.column(-3000, z)
+		for x := -2999; x <= 3000; x++ {
+			column := wg.column(x, z)
+			if column.Biome != prev.Biome {
+				borders++
+				if d := column.Height - prev.Height; d > 3 || d < -3 {
+					t.Errorf("expected a smooth height transition from %s to %s at %d,%d, height changed by %d",
+						prev.Biome, column.Biome, x, z, d)
+				}
+			}
+			prev = column
+		}
+	}
+	if borders == 0 {
+		t.Error("expected to cross biome borders")
+	}
+}
+
+func TestBiomeSurface(t *testing.T) {
+	wg := testWorldgen(t, 31481234, 16)
+	for x := 0; x < 64; x++ {
+		column := wg.column(x, 7*x)
+		surface := wg.voxel(column, x, column.Height, 7*x)
+		if surface == EmptyVoxel {
+			// carved out by a cave
+			continue
+		}
+		if surface.Block != column.Biome.Surface.Block {
+			t.Errorf("expected %s surface at %d, found %+v", column.Biome, x, surface)
+		}
+		above := wg.voxel(column, x, column.Height+1, 7*x)
+		if column.Feature == nil && above != EmptyVoxel {
+			t.Errorf("expected air above the surface at %d, found %+v", x, above)
+		}
+		if column.Feature != nil && above.Block != column.Feature.Voxel.Block {
+			t.Errorf("expected a feature above the surface at %d, found %+v", x, above)
+		}
+	}
+}
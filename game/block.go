@@ -0,0 +1,148 @@
+package game
+
+import (
+	"fmt"
+)
+
+// BlockID identifies a block type. Block IDs are stored in chunk files, so the ID of
+// a block type must never change once it has been registered.
+type BlockID uint16
+
+const (
+	// AirBlock is empty space
+	AirBlock BlockID = iota
+
+	// ColorBlock is a plain solid block, colored by its tint. Voxels from chunk files
+	// saved before block types existed are loaded as color blocks.
+	ColorBlock
+
+	// GlassBlock is a solid block that lets light through
+	GlassBlock
+
+	// WaterBlock is a liquid
+	WaterBlock
+
+	// LampBlock is a solid block emitting light of its tint color
+	LampBlock
+
+	// IceBlock is a slippery, translucent solid block
+	IceBlock
+
+	// BedrockBlock is a solid block that can not be destroyed
+	BedrockBlock
+
+	// LavaBlock is a slow, glowing liquid
+	LavaBlock
+)
+
+// BlockType holds the properties shared by every voxel of the same type
+type BlockType struct {
+	ID   BlockID
+	Name string
+
+	// Solid blocks can not be walked through
+	Solid bool
+
+	// Opaque blocks stop light, and hide the faces of neighboring blocks
+	Opaque bool
+
+	// Liquid blocks flow. See Flow and FlowDelay
+	Liquid bool
+
+	// Indestructible blocks can not be removed or replaced by the editor
+	Indestructible bool
+
+	// Friction scales the ground friction of a player standing on the block.
+	// 1 is regular ground, lower values are slippery.
+	Friction float32
+
+	// Alpha is the opacity of the faces of blocks that are not opaque. Blocks with
+	// a non-zero alpha are translucent, and drawn with blending in the forward pass.
+	Alpha byte
+
+	// Flow is the number of voxels a liquid spreads sideways from its source
+	Flow byte
+
+	// FlowDelay is the number of fluid ticks it takes a liquid to flow one voxel
+	FlowDelay byte
+
+	// Light is the radius of the light emitted by the block, in voxels.
+	// The light has the same color as the voxel tint.
+	Light byte
+}
+
+// String returns the display name of the block type
+func (b *BlockType) String() string {
+	return b.Name
+}
+
+// Translucent returns true if the block can be seen through, but is still visible
+func (b *BlockType) Translucent() bool {
+	return !b.Opaque && b.Alpha > 0
+}
+
+var blockTypes []*BlockType
+
+// unknownBlock is returned for block IDs that have not been registered, for example
+// when loading a chunk saved by a build with more block types
+var unknownBlock = &BlockType{
+	Name:     "Unknown",
+	Solid:    true,
+	Opaque:   true,
+	Friction: 1,
+}
+
+// RegisterBlock adds a block type to the block registry. Registering the same ID twice panics.
+func RegisterBlock(block BlockType) *BlockType {
+	id := int(block.ID)
+	for len(blockTypes) <= id {
+		blockTypes = append(blockTypes, nil)
+	}
+	if blockTypes[id] != nil {
+		panic(fmt.Errorf("block type %d is already registered as %s", id, blockTypes[id]))
+	}
+	blockTypes[id] = &block
+	return &block
+}
+
+// Block returns the block type with the given ID. Unregistered IDs return a solid placeholder type.
+func Block(id BlockID) *BlockType {
+	if int(id) < len(blockTypes) {
+		if block := blockTypes[id]; block != nil {
+			return block
+		}
+	}
+	return unknownBlock
+}
+
+// BlockByName returns the registered block type with the given name
+func BlockByName(name string) (*BlockType, bool) {
+	for _, block := range blockTypes {
+		if block != nil && block.Name == name {
+			return block, true
+		}
+	}
+	return nil, false
+}
+
+// BlockTypes returns all registered block types, ordered by ID
+func BlockTypes() []*BlockType {
+	types := make([]*BlockType, 0, len(blockTypes))
+	for _, block := range blockTypes {
+		if block != nil {
+			types = append(types, block)
+		}
+	}
+	return types
+}
+
+func init() {
+	RegisterBlock(BlockType{ID: AirBlock, Name: "Air"})
+	RegisterBlock(BlockType{ID: ColorBlock, Name: "Color", Solid: true, Opaque: true, Friction: 1})
+	RegisterBlock(BlockType{ID: GlassBlock, Name: "Glass", Solid: true, Friction: 1, Alpha: 80})
+	RegisterBlock(BlockType{ID: WaterBlock, Name: "Water", Liquid: true, Alpha: 160, Flow: 7, FlowDelay: 4})
+	RegisterBlock(BlockType{ID: LampBlock, Name: "Lamp", Solid: true, Opaque: true, Friction: 1, Light: 10})
+	RegisterBlock(BlockType{ID: IceBlock, Name: "Ice", Solid: true, Friction: 0.15, Alpha: 200})
+	RegisterBlock(BlockType{ID: BedrockBlock, Name: "Bedrock", Solid: true, Opaque: true, Friction: 1, Indestructible: true})
+	RegisterBlock(BlockType{ID: LavaBlock, Name: "Lava", Liquid: true, Opaque: true, Light: 12, Flow: 3, FlowDelay: 20})
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+func TestBlockRegistry(t *testing.T) {
+	if Block(AirBlock).Solid || Block(AirBlock).Opaque {
+		t.Error("expected air to be empty")
+	}
+	if b := Block(ColorBlock); !b.Solid || !b.Opaque {
+		t.Errorf("expected color blocks to be solid and opaque, was %+v", b)
+	}
+	if b := Block(BlockID(999)); b != unknownBlock || !b.Solid {
+		t.Errorf("expected unregistered block ids to be solid placeholders, was %+v", b)
+	}
+
+	types := BlockTypes()
+	for i, b := range types {
+		if i > 0 && b.ID <= types[i-1].ID {
+			t.Errorf("expected block types ordered by id")
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a duplicate block id to panic")
+		}
+	}()
+	RegisterBlock(BlockType{ID: ColorBlock, Name: "Duplicate"})
+}
+
+func TestVoxelEmission(t *testing.T) {
+	radius := Block(LampBlock).Light
+	cases := []struct {
+		Voxel    Voxel
+		Expected [3]byte
+	}{
+		{Voxel{Block: ColorBlock, R: 255, G: 255, B: 255}, [3]byte{}},
+		{Voxel{Block: LampBlock, R: 255, G: 255, B: 255}, [3]byte{radius, radius, radius}},
+		{Voxel{Block: LampBlock, R: 100}, [3]byte{radius, 0, 0}},
+		{Voxel{Block: LampBlock, R: 200, G: 100}, [3]byte{radius, radius / 2, 0}},
+	}
+	for _, c := range cases {
+		if e := c.Voxel.Emission(); e != c.Expected {
+			t.Errorf("expected %+v to emit %v, was %v", c.Voxel, c.Expected, e)
+		}
+	}
+}
+
+func TestTransparentBlocks(t *testing.T) {
+	chunk := NewChunk(4, 0, 0, 0, 0)
+	glass := Voxel{Block: GlassBlock, R: 200, G: 200, B: 255}
+	chunk.Set(1, 1, 1, glass)
+	chunk.Set(2, 1, 1, glass)
+	chunk.Light.Calculate()
+
+	// two panes of glass form a 2x1x1 box, with no faces in between
+	if n := len(computeQuads(NewNeighborhood(chunk, nil))); n != 10 {
+		t.Errorf("expected 10 faces, got %d", n)
+	}
+
+	// glass lets light through, so the voxel below is lit by the sky
+	if v := chunk.Light.Get(1, 0, 1).V; v != 1 {
+		t.Errorf("expected sky light below glass, got %f", v)
+	}
+
+	// a stone below the glass shows its top face through the glass
+	chunk.Set(1, 0, 1, Voxel{Block: ColorBlock, R: 100})
+	faces := 0
+	for _, q := range computeQuads(NewNeighborhood(chunk, nil)) {
+		if q.X == 1 && q.Y == 1 && q.Z == 1 && q.V[0].R == 100 {
+			faces++
+		}
+	}
+	if faces != 1 {
+		t.Errorf("expected the stone face below the glass to be visible")
+	}
+}
+
+func TestTranslucentMesh(t *testing.T) {
+	chunk := NewChunk(4, 0, 0, 0, 0)
+	glass := Voxel{Block: GlassBlock, R: 200, G: 200, B: 255}
+	chunk.Set(1, 1, 1, glass)
+	chunk.Set(2, 1, 1, glass)
+	chunk.Set(1, 0, 1, Voxel{Block: ColorBlock, R: 100})
+	chunk.Light.Calculate()
+
+	data := computeVertexData(NewNeighborhood(chunk, nil), MeshSimple, 0)
+
+	// the glass box is missing the face resting on the stone
+	if n := len(data.Translucent) / 6; n != 9 {
+		t.Errorf("expected 9 translucent faces, got %d", n)
+	}
+	for _, v := range data.Translucent {
+		if v.A != Block(GlassBlock).Alpha || v.R != 200 {
+			t.Fatalf("expected translucent vertices to be glass, was %+v", v)
+		}
+	}
+
+	stoneTop := false
+	for _, v := range data.Opaque {
+		if v.A != 255 || v.R != 100 {
+			t.Fatalf("expected opaque vertices to be stone, was %+v", v)
+		}
+		stoneTop = stoneTop || v.N == 3
+	}
+	if !stoneTop {
+		t.Error("expected the stone face below the glass in the opaque mesh")
+	}
+}
+
+func TestHeightAtIgnoresLiquids(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	world.AddChunk(0, 0, 0)
+	world.Set(1, 0, 1, Voxel{Block: ColorBlock, R: 1})
+	world.Set(1, 1, 1, Voxel{Block: WaterBlock, B: 255})
+	if h := world.HeightAt(vec3.New(1.5, 3, 1.5)); h != 1 {
+		t.Errorf("expected to stand on the block below the water at height 1, was %f", h)
+	}
+	if b := world.BlockAt(vec3.New(1.5, 1.5, 1.5)); b.ID != WaterBlock {
+		t.Errorf("expected water, was %s", b)
+	}
+}
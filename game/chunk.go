@@ -1,7 +1,6 @@
 package game
 
 import (
-	"encoding/gob"
 	"fmt"
 	"os"
 )
@@ -9,21 +8,24 @@ import (
 // Chunk is the smallest individually renderable unit of voxel geometry
 type Chunk struct {
 	Seed       int
-	Cx, Cz     int
+	Cx, Cy, Cz int
 	Ox, Oy, Oz int
 	Sx, Sy, Sz int
-	Data       Voxels
+	Data       *PalettedVoxels
 	Light      *LightVolume
 }
 
-func NewChunk(size, seed, cx, cz int) *Chunk {
+// NewChunk creates a new empty chunk at the given chunk coordinates.
+func NewChunk(size, seed, cx, cy, cz int) *Chunk {
 	return &Chunk{
-		Data:  make(Voxels, size*size*size),
+		Data:  NewPalettedVoxels(size * size * size),
 		Light: NewLightVolume(size, size+1, size),
 		Seed:  seed,
 		Cx:    cx,
+		Cy:    cy,
 		Cz:    cz,
 		Ox:    cx * size,
+		Oy:    cy * size,
 		Oz:    cz * size,
 		Sx:    size,
 		Sy:    size,
@@ -31,11 +33,18 @@ func NewChunk(size, seed, cx, cz int) *Chunk {
 	}
 }
 
+// Snapshot returns a copy of the chunk with its own voxel and light data, which can be read by
+// background workers while the original chunk is modified
+func (c *Chunk) Snapshot() *Chunk {
+	snapshot := *c
+	snapshot.Data = c.Data.Clone()
+	snapshot.Light = c.Light.Clone()
+	return &snapshot
+}
+
 // Clear all voxel data in this chunk
 func (c *Chunk) Clear() {
-	for i := 0; i < len(c.Data); i++ {
-		c.Data[i] = EmptyVoxel
-	}
+	c.Data.Fill(EmptyVoxel)
 	c.Light.Clear()
 }
 
@@ -50,6 +59,12 @@ func (c *Chunk) offset(x, y, z int) (int, bool) {
 	return pos, true
 }
 
+// Contains returns true if the given local position is within the chunk
+func (c *Chunk) Contains(x, y, z int) bool {
+	_, ok := c.offset(x, y, z)
+	return ok
+}
+
 /* Returns a pointer to the voxel defintion at the given position.
    If the space is empty, nil is returned */
 func (c *Chunk) At(x, y, z int) Voxel {
@@ -57,7 +72,7 @@ func (c *Chunk) At(x, y, z int) Voxel {
 	if !ok {
 		return EmptyVoxel
 	}
-	return c.Data[pos]
+	return c.Data.Get(pos)
 }
 
 // Set a voxel. If it's out of bounds, nothing happens
@@ -66,8 +81,9 @@ func (c *Chunk) Set(x, y, z int, voxel Voxel) {
 	if !ok {
 		return
 	}
-	c.Data[pos] = voxel
-	c.Light.Block(x, y, z, voxel != EmptyVoxel)
+	c.Data.Set(pos, voxel)
+	c.Light.Block(x, y, z, voxel.Type().Opaque)
+	c.Light.Emit(x, y, z, voxel.Emission())
 }
 
 // Free returns true if the given position is open
@@ -77,41 +93,64 @@ func (c *Chunk) Free(x, y, z int) bool {
 		return true
 	}
 	c.Light.Block(x, y, z, false)
-	return c.Data[v] == EmptyVoxel
+	return !c.Data.Get(v).Type().Solid
+}
+
+// chunkFile returns the file name of the chunk at the given chunk coordinates
+func chunkFile(path string, cx, cy, cz int) string {
+	return fmt.Sprintf("%s/c_%d_%d_%d.bin", path, cx, cy, cz)
+}
+
+// legacyChunkFile returns the file name used before chunks were stacked vertically.
+// Such worlds were a single chunk tall, so it only applies to cy = 0.
+func legacyChunkFile(path string, cx, cz int) string {
+	return fmt.Sprintf("%s/c_%d_%d.bin", path, cx, cz)
 }
 
 // Write the chunk to disk
 func (c *Chunk) Write(path string) error {
-	filepath := fmt.Sprintf("%s/c_%d_%d.bin", path, c.Cx, c.Cz)
+	filepath := chunkFile(path, c.Cx, c.Cy, c.Cz)
 	file, err := os.Create(filepath)
 	defer file.Close()
 	if err != nil {
 		return err
 	}
-	encoder := gob.NewEncoder(file)
-	err = encoder.Encode(c)
+	err = encodeChunk(file, c)
 	if err == nil {
-		fmt.Printf("Wrote chunk %d,%d to disk\n", c.Cx, c.Cz)
+		fmt.Printf("Wrote chunk %d,%d,%d to disk\n", c.Cx, c.Cy, c.Cz)
 	} else {
-		fmt.Printf("Error writing chunk %d,%d: %s\n", c.Cx, c.Cz, err)
+		fmt.Printf("Error writing chunk %d,%d,%d: %s\n", c.Cx, c.Cy, c.Cz, err)
 	}
 	return err
 }
 
-func LoadChunk(path string, cx, cz int) (*Chunk, error) {
-	filepath := fmt.Sprintf("%s/c_%d_%d.bin", path, cx, cz)
-	file, err := os.Open(filepath)
-	defer file.Close()
+// findChunkFile returns the name of the file holding the given chunk,
+// falling back to the legacy file name if it exists.
+func findChunkFile(path string, cx, cy, cz int) string {
+	name := chunkFile(path, cx, cy, cz)
+	if cy == 0 {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			legacy := legacyChunkFile(path, cx, cz)
+			if _, err := os.Stat(legacy); err == nil {
+				return legacy
+			}
+		}
+	}
+	return name
+}
+
+// LoadChunk reads the chunk at the given chunk coordinates from disk
+func LoadChunk(path string, cx, cy, cz int) (*Chunk, error) {
+	file, err := os.Open(findChunkFile(path, cx, cy, cz))
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
-	decoder := gob.NewDecoder(file)
-	chunk := &Chunk{}
-	err = decoder.Decode(chunk)
+	chunk, err := decodeChunk(file)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("Read chunk %d,%d from disk\n", chunk.Cx, chunk.Cz)
+	fmt.Printf("Read chunk %d,%d,%d from disk\n", chunk.Cx, chunk.Cy, chunk.Cz)
 	return chunk, nil
 }
@@ -0,0 +1,411 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// ChunkMagic identifies serialized chunk data
+var ChunkMagic = [4]byte{'G', 'W', 'C', 'K'}
+
+// ChunkFormatVersion is the current chunk serialization format version.
+//
+//	1: gob encoded chunk struct without header
+//	2: binary format with paletted voxel data
+//	3: palette entries are tinted block types with liquid levels
+const ChunkFormatVersion = 3
+
+// MaxChunkSize is the largest chunk size accepted when decoding serialized chunks
+const MaxChunkSize = 256
+
+// ErrCorruptChunk is returned when serialized chunk data fails validation
+var ErrCorruptChunk = errors.New("corrupt chunk data")
+
+// ErrUnsupportedVersion is returned for stored data written in a newer format than this build can read.
+// Such data is not corrupt, and must be left untouched.
+var ErrUnsupportedVersion = errors.New("unsupported format version")
+
+// ChunkMigration upgrades a serialized chunk payload to the next format version
+type ChunkMigration func(payload []byte) ([]byte, error)
+
+var chunkMigrations = map[int]ChunkMigration{}
+
+// RegisterChunkMigration registers a migration from the given format version to the next
+func RegisterChunkMigration(from int, migration ChunkMigration) {
+	if _, exists := chunkMigrations[from]; exists {
+		panic(fmt.Errorf("chunk migration from version %d is already registered", from))
+	}
+	chunkMigrations[from] = migration
+}
+
+func init() {
+	RegisterChunkMigration(1, migrateGobChunk)
+	RegisterChunkMigration(2, migrateBlockTypes)
+}
+
+// chunkHeader precedes every serialized chunk payload
+type chunkHeader struct {
+	Magic    [4]byte
+	Version  uint16
+	Flags    uint16
+	Length   uint32
+	Checksum uint32
+}
+
+// encodeChunk serializes a chunk to a writer
+func encodeChunk(w io.Writer, c *Chunk) error {
+	payload, err := marshalChunk(c)
+	if err != nil {
+		return err
+	}
+	header := chunkHeader{
+		Magic:    ChunkMagic,
+		Version:  ChunkFormatVersion,
+		Length:   uint32(len(payload)),
+		Checksum: crc32.ChecksumIEEE(payload),
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
+
+// decodeChunk deserializes a chunk from a reader, migrating older formats if required.
+// Data that fails validation results in an error wrapping ErrCorruptChunk, and data written in a
+// newer format in an error wrapping ErrUnsupportedVersion.
+func decodeChunk(r io.Reader) (*Chunk, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	version, payload, err := readChunkHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for version < ChunkFormatVersion {
+		migrate, exists := chunkMigrations[version]
+		if !exists {
+			return nil, fmt.Errorf("no chunk migration from format version %d", version)
+		}
+		if payload, err = migrate(payload); err != nil {
+			return nil, fmt.Errorf("%w: migration from version %d failed: %s", ErrCorruptChunk, version, err)
+		}
+		version++
+	}
+
+	return unmarshalChunk(payload)
+}
+
+// readChunkHeader validates the chunk header and returns the format version and payload.
+// Data without a header is assumed to be a version 1 gob chunk.
+func readChunkHeader(data []byte) (int, []byte, error) {
+	header := chunkHeader{}
+	size := binary.Size(header)
+	if len(data) < len(ChunkMagic) || !bytes.Equal(data[:len(ChunkMagic)], ChunkMagic[:]) {
+		return 1, data, nil
+	}
+	if len(data) < size {
+		return 0, nil, fmt.Errorf("%w: truncated header", ErrCorruptChunk)
+	}
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil {
+		return 0, nil, err
+	}
+	if header.Version == 0 {
+		return 0, nil, fmt.Errorf("%w: invalid format version", ErrCorruptChunk)
+	}
+	if header.Version > ChunkFormatVersion {
+		return 0, nil, fmt.Errorf("%w: chunk format version %d", ErrUnsupportedVersion, header.Version)
+	}
+
+	payload := data[size:]
+	if int(header.Length) != len(payload) {
+		return 0, nil, fmt.Errorf("%w: expected %d bytes of payload, found %d", ErrCorruptChunk, header.Length, len(payload))
+	}
+	if crc32.ChecksumIEEE(payload) != header.Checksum {
+		return 0, nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptChunk)
+	}
+	return int(header.Version), payload, nil
+}
+
+// chunkInfo is the fixed size part of a chunk payload.
+// It is followed by the voxel palette, the voxel indices and the light volume.
+type chunkInfo struct {
+	Seed       int64
+	Cx, Cy, Cz int32
+	Sx, Sy, Sz int32
+	Palette    uint32
+	Bits       uint8
+	Words      uint32
+}
+
+// lightInfo describes the light volume in a chunk payload.
+// It is followed by the blocked flags and the sky light values, in the volume's memory order.
+// Block light is not stored, since it is recomputed from the emitting voxels on load.
+type lightInfo struct {
+	Falloff    float32
+	Sx, Sy, Sz int32
+}
+
+// rgbVoxel is a palette entry in a version 2 chunk payload
+type rgbVoxel struct {
+	R, G, B byte
+}
+
+// marshalChunk encodes a chunk as a payload of the current format version
+func marshalChunk(c *Chunk) ([]byte, error) {
+	return marshalChunkVersion(c, ChunkFormatVersion)
+}
+
+// marshalChunkVersion encodes a chunk as a payload of the given version. Besides the current
+// version, only version 2 is supported, which is used when migrating version 1 chunks.
+func marshalChunkVersion(c *Chunk, version int) ([]byte, error) {
+	// work on a compacted copy so that the chunk itself is left untouched
+	voxels := NewPalettedVoxels(0)
+	voxels.Load(c.Data.Voxels())
+
+	buffer := &bytes.Buffer{}
+	info := chunkInfo{
+		Seed:    int64(c.Seed),
+		Cx:      int32(c.Cx),
+		Cy:      int32(c.Cy),
+		Cz:      int32(c.Cz),
+		Sx:      int32(c.Sx),
+		Sy:      int32(c.Sy),
+		Sz:      int32(c.Sz),
+		Palette: uint32(len(voxels.palette)),
+		Bits:    uint8(voxels.bits),
+		Words:   uint32(len(voxels.data)),
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, info); err != nil {
+		return nil, err
+	}
+	var palette interface{} = voxels.palette
+	if version == 2 {
+		rgb := make([]rgbVoxel, len(voxels.palette))
+		for i, v := range voxels.palette {
+			rgb[i] = rgbVoxel{v.R, v.G, v.B}
+		}
+		palette = rgb
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, palette); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, voxels.data); err != nil {
+		return nil, err
+	}
+
+	lv := c.Light
+	light := lightInfo{
+		Falloff: lv.Falloff,
+		Sx:      int32(lv.Sx),
+		Sy:      int32(lv.Sy),
+		Sz:      int32(lv.Sz),
+	}
+	blocked := make([]bool, 0, lv.Sx*lv.Sy*lv.Sz)
+	values := make([]float32, 0, lv.Sx*lv.Sy*lv.Sz)
+	for z := 0; z < lv.Sz; z++ {
+		for x := 0; x < lv.Sx; x++ {
+			for y := 0; y < lv.Sy; y++ {
+				blocked = append(blocked, lv.Data[z][x][y].Blocked)
+				values = append(values, lv.Data[z][x][y].V)
+			}
+		}
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, light); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, blocked); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, values); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// unmarshalChunk decodes a chunk payload of the current format version
+func unmarshalChunk(payload []byte) (*Chunk, error) {
+	corrupt := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrCorruptChunk, reason)
+	}
+
+	reader := bytes.NewReader(payload)
+	info := chunkInfo{}
+	if err := binary.Read(reader, binary.LittleEndian, &info); err != nil {
+		return nil, corrupt("truncated chunk info")
+	}
+	if info.Sx <= 0 || info.Sx > MaxChunkSize || info.Sx != info.Sy || info.Sx != info.Sz {
+		return nil, corrupt("invalid chunk size")
+	}
+	size := int(info.Sx) * int(info.Sy) * int(info.Sz)
+	if info.Palette == 0 || int(info.Palette) > size || info.Bits > 32 {
+		return nil, corrupt("invalid palette")
+	}
+
+	palette := make(Voxels, info.Palette)
+	if err := binary.Read(reader, binary.LittleEndian, palette); err != nil {
+		return nil, corrupt("truncated palette")
+	}
+
+	voxels := NewPalettedVoxels(size)
+	voxels.Fill(palette[0])
+	if info.Bits > 0 {
+		per := 64 / int(info.Bits)
+		if int(info.Words) != (size+per-1)/per {
+			return nil, corrupt("invalid voxel data length")
+		}
+		packed := &PalettedVoxels{
+			size: size,
+			bits: uint(info.Bits),
+			data: make([]uint64, info.Words),
+		}
+		if err := binary.Read(reader, binary.LittleEndian, packed.data); err != nil {
+			return nil, corrupt("truncated voxel data")
+		}
+		flat := make(Voxels, size)
+		for i := range flat {
+			idx := packed.index(i)
+			if idx >= len(palette) {
+				return nil, corrupt("palette index out of range")
+			}
+			flat[i] = palette[idx]
+		}
+		voxels.Load(flat)
+	}
+
+	light := lightInfo{}
+	if err := binary.Read(reader, binary.LittleEndian, &light); err != nil {
+		return nil, corrupt("truncated light info")
+	}
+	if light.Sx != info.Sx || light.Sy <= 0 || light.Sz != info.Sz || light.Sy > 2*info.Sy {
+		return nil, corrupt("invalid light volume size")
+	}
+	count := int(light.Sx) * int(light.Sy) * int(light.Sz)
+	blocked := make([]bool, count)
+	values := make([]float32, count)
+	if err := binary.Read(reader, binary.LittleEndian, blocked); err != nil {
+		return nil, corrupt("truncated light data")
+	}
+	if err := binary.Read(reader, binary.LittleEndian, values); err != nil {
+		return nil, corrupt("truncated light data")
+	}
+	if reader.Len() != 0 {
+		return nil, corrupt("trailing data")
+	}
+
+	lv := NewLightVolume(int(light.Sx), int(light.Sy), int(light.Sz))
+	lv.Falloff = light.Falloff
+	i := 0
+	for z := 0; z < lv.Sz; z++ {
+		for x := 0; x < lv.Sx; x++ {
+			for y := 0; y < lv.Sy; y++ {
+				lv.Data[z][x][y] = LightVoxel{Blocked: blocked[i], V: values[i]}
+				i++
+			}
+		}
+	}
+
+	s := int(info.Sx)
+	chunk := NewChunk(s, int(info.Seed), int(info.Cx), int(info.Cy), int(info.Cz))
+	chunk.Data = voxels
+	chunk.Light = lv
+	restoreEmission(chunk)
+	return chunk, nil
+}
+
+// restoreEmission marks the emitting voxels of a chunk in its light volume,
+// and recomputes its light if there are any.
+func restoreEmission(chunk *Chunk) {
+	emitters := false
+	for z := 0; z < chunk.Sz; z++ {
+		for y := 0; y < chunk.Sy; y++ {
+			for x := 0; x < chunk.Sx; x++ {
+				if emission := chunk.At(x, y, z).Emission(); emission != [3]byte{} {
+					chunk.Light.Emit(x, y, z, emission)
+					emitters = true
+				}
+			}
+		}
+	}
+	if emitters {
+		chunk.Light.Calculate()
+	}
+}
+
+// gobChunk is the version 1 chunk format, a gob encoded struct
+type gobChunk struct {
+	Seed       int
+	Cx, Cy, Cz int
+	Ox, Oy, Oz int
+	Sx, Sy, Sz int
+	Data       Voxels
+	Light      *LightVolume
+}
+
+// migrateGobChunk converts a version 1 gob chunk into a version 2 payload
+func migrateGobChunk(payload []byte) ([]byte, error) {
+	old := gobChunk{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&old); err != nil {
+		return nil, err
+	}
+	if old.Sx <= 0 || old.Sx > MaxChunkSize || old.Sx != old.Sy || old.Sx != old.Sz || len(old.Data) != old.Sx*old.Sy*old.Sz {
+		return nil, fmt.Errorf("invalid chunk dimensions")
+	}
+	if old.Light == nil {
+		return nil, fmt.Errorf("missing light volume")
+	}
+
+	chunk := NewChunk(old.Sx, old.Seed, old.Cx, old.Cy, old.Cz)
+	chunk.Data.Load(old.Data)
+	chunk.Light = old.Light
+	return marshalChunkVersion(chunk, 2)
+}
+
+// migratePalette rewrites the palette entries of a version 2 or later payload, leaving the
+// rest of the payload untouched. Each old entry of entrySize bytes is passed to convert, which
+// returns the new entry.
+func migratePalette(payload []byte, entrySize int, convert func(entry []byte) interface{}) ([]byte, error) {
+	info := chunkInfo{}
+	if err := binary.Read(bytes.NewReader(payload), binary.LittleEndian, &info); err != nil {
+		return nil, fmt.Errorf("truncated chunk info")
+	}
+	start := binary.Size(info)
+	end := start + int(info.Palette)*entrySize
+	if int(info.Palette) > len(payload) || end > len(payload) {
+		return nil, fmt.Errorf("truncated palette")
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := binary.Write(buffer, binary.LittleEndian, info); err != nil {
+		return nil, err
+	}
+	for i := start; i < end; i += entrySize {
+		if err := binary.Write(buffer, binary.LittleEndian, convert(payload[i:i+entrySize])); err != nil {
+			return nil, err
+		}
+	}
+	buffer.Write(payload[end:])
+	return buffer.Bytes(), nil
+}
+
+// migrateBlockTypes converts a version 2 payload into version 3 by assigning a block type to
+// each palette entry. Black voxels were empty, and all other voxels become color blocks.
+// Liquids did not exist, so every liquid level is zero.
+func migrateBlockTypes(payload []byte) ([]byte, error) {
+	return migratePalette(payload, 3, func(e []byte) interface{} {
+		if e[0] == 0 && e[1] == 0 && e[2] == 0 {
+			return Voxel{Block: AirBlock}
+		}
+		return Voxel{Block: ColorBlock, R: e[0], G: e[1], B: e[2]}
+	})
+}
@@ -0,0 +1,255 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func testChunk() *Chunk {
+	chunk := NewChunk(8, 42, 1, -2, 3)
+	chunk.Set(1, 2, 3, Voxel{Block: ColorBlock, R: 10, G: 20, B: 30})
+	chunk.Set(7, 7, 7, Voxel{Block: ColorBlock, R: 40, G: 50, B: 60})
+	chunk.Light.Calculate()
+	return chunk
+}
+
+func assertChunksEqual(t *testing.T, expected, actual *Chunk) {
+	t.Helper()
+	if actual.Seed != expected.Seed || actual.Cx != expected.Cx || actual.Cy != expected.Cy || actual.Cz != expected.Cz {
+		t.Fatalf("chunk header mismatch: %+v", actual)
+	}
+	if actual.Ox != expected.Ox || actual.Oy != expected.Oy || actual.Oz != expected.Oz {
+		t.Errorf("chunk origin mismatch")
+	}
+	for i, v := range expected.Data.Voxels() {
+		if actual.Data.Get(i) != v {
+			t.Fatalf("voxel %d mismatch: expected %v, was %v", i, v, actual.Data.Get(i))
+		}
+	}
+	for z := 0; z < expected.Light.Sz; z++ {
+		for x := 0; x < expected.Light.Sx; x++ {
+			for y := 0; y < expected.Light.Sy; y++ {
+				if *actual.Light.Get(x, y, z) != *expected.Light.Get(x, y, z) {
+					t.Fatalf("light mismatch at %d,%d,%d", x, y, z)
+				}
+			}
+		}
+	}
+}
+
+func TestChunkFormatRoundTrip(t *testing.T) {
+	chunk := testChunk()
+	chunk.Set(4, 4, 4, Voxel{Block: LampBlock, R: 250, G: 200, B: 100})
+	chunk.Set(5, 4, 4, Voxel{Block: WaterBlock, B: 255, Level: 3})
+	chunk.Light.Calculate()
+	buffer := &bytes.Buffer{}
+	if err := encodeChunk(buffer, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buffer.Bytes(), ChunkMagic[:]) {
+		t.Error("expected encoded chunk to begin with magic bytes")
+	}
+
+	decoded, err := decodeChunk(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChunksEqual(t, chunk, decoded)
+}
+
+func TestChunkFormatMigratesGob(t *testing.T) {
+	chunk := testChunk()
+	legacy := &bytes.Buffer{}
+	err := gob.NewEncoder(legacy).Encode(gobChunk{
+		Seed: chunk.Seed,
+		Cx:   chunk.Cx, Cy: chunk.Cy, Cz: chunk.Cz,
+		Ox: chunk.Ox, Oy: chunk.Oy, Oz: chunk.Oz,
+		Sx: chunk.Sx, Sy: chunk.Sy, Sz: chunk.Sz,
+		Data:  chunk.Data.Voxels(),
+		Light: chunk.Light,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeChunk(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChunksEqual(t, chunk, decoded)
+}
+
+func TestChunkFormatMigratesVersion2(t *testing.T) {
+	chunk := NewChunk(8, 42, 1, -2, 3)
+	chunk.Set(1, 2, 3, Voxel{Block: ColorBlock, R: 10, G: 20, B: 30})
+	chunk.Light.Calculate()
+
+	payload, err := marshalChunkVersion(chunk, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &bytes.Buffer{}
+	header := chunkHeader{
+		Magic:    ChunkMagic,
+		Version:  2,
+		Length:   uint32(len(payload)),
+		Checksum: crc32.ChecksumIEEE(payload),
+	}
+	if err := binary.Write(buffer, binary.LittleEndian, header); err != nil {
+		t.Fatal(err)
+	}
+	buffer.Write(payload)
+
+	decoded, err := decodeChunk(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChunksEqual(t, chunk, decoded)
+}
+
+func TestChunkFormatDetectsCorruption(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	if err := encodeChunk(buffer, testChunk()); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)-5] ^= 0xFF
+	if _, err := decodeChunk(bytes.NewReader(flipped)); !errors.Is(err, ErrCorruptChunk) {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+
+	truncated := data[:len(data)/2]
+	if _, err := decodeChunk(bytes.NewReader(truncated)); !errors.Is(err, ErrCorruptChunk) {
+		t.Errorf("expected truncation error, got %v", err)
+	}
+
+	garbage := []byte("definitely not a chunk")
+	if _, err := decodeChunk(bytes.NewReader(garbage)); !errors.Is(err, ErrCorruptChunk) {
+		t.Errorf("expected garbage to be reported as corrupt, got %v", err)
+	}
+}
+
+func TestChunkFormatRejectsLargeChunks(t *testing.T) {
+	// a uniform chunk has no voxel data, so the light volume directly follows the palette
+	payload, err := marshalChunk(NewChunk(8, 0, 0, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := chunkInfo{}
+	light := lightInfo{}
+	reader := bytes.NewReader(payload)
+	if err := binary.Read(reader, binary.LittleEndian, &info); err != nil {
+		t.Fatal(err)
+	}
+	palette := make(Voxels, info.Palette)
+	if err := binary.Read(reader, binary.LittleEndian, palette); err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &light); err != nil {
+		t.Fatal(err)
+	}
+	rest := payload[len(payload)-reader.Len():]
+
+	// sizes above the maximum are rejected before anything is allocated, including sizes
+	// that wrap around when multiplied in 32 bits
+	for _, size := range []int32{MaxChunkSize + 1, 1000, 1625, 2048} {
+		info.Sx, info.Sy, info.Sz = size, size, size
+		light.Sx, light.Sy, light.Sz = size, size, size
+
+		resized := &bytes.Buffer{}
+		for _, part := range []interface{}{info, palette, light, rest} {
+			if err := binary.Write(resized, binary.LittleEndian, part); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data := &bytes.Buffer{}
+		header := chunkHeader{
+			Magic:    ChunkMagic,
+			Version:  ChunkFormatVersion,
+			Length:   uint32(resized.Len()),
+			Checksum: crc32.ChecksumIEEE(resized.Bytes()),
+		}
+		if err := binary.Write(data, binary.LittleEndian, header); err != nil {
+			t.Fatal(err)
+		}
+		data.Write(resized.Bytes())
+
+		before := runtime.MemStats{}
+		runtime.ReadMemStats(&before)
+		if _, err := decodeChunk(data); !errors.Is(err, ErrCorruptChunk) {
+			t.Errorf("expected chunk size %d to be rejected as corrupt, got %v", size, err)
+		}
+		after := runtime.MemStats{}
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("expected chunk size %d to be rejected without allocating, allocated %d bytes", size, allocated)
+		}
+	}
+}
+
+func TestFileStoreQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileStore(dir)
+	name := chunkFile(dir, 0, 0, 0)
+	if err := ioutil.WriteFile(name, []byte("GWCK broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(0, 0, 0); !errors.Is(err, ErrCorruptChunk) {
+		t.Fatalf("expected corrupt chunk error, got %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Error("expected corrupt chunk file to be moved")
+	}
+	quarantined, _ := filepath.Glob(filepath.Join(dir, QuarantineDir, "c_0_0_0.bin.*"))
+	if len(quarantined) != 1 {
+		t.Errorf("expected corrupt chunk in quarantine, found %d files", len(quarantined))
+	}
+}
+
+func TestRegionStoreQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewRegionStore(dir, 2)
+	if err := store.Save(testChunk()); err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the last byte of the chunk data
+	name, _ := store.region(1, -2, 3)
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(1, -2, 3); !errors.Is(err, ErrCorruptChunk) {
+		t.Fatalf("expected corrupt chunk error, got %v", err)
+	}
+	quarantined, _ := filepath.Glob(filepath.Join(dir, QuarantineDir, "c_1_-2_3.bin.*"))
+	if len(quarantined) != 1 {
+		t.Errorf("expected corrupt chunk data in quarantine, found %d files", len(quarantined))
+	}
+}
@@ -0,0 +1,173 @@
+package game
+
+// MaxLOD is the coarsest level of detail of chunk meshes.
+// At level n, each cube of 2^n voxels along each axis is merged into a single voxel.
+const MaxLOD = 3
+
+// LODSelector picks the level of detail of a chunk mesh from its distance to the camera
+type LODSelector struct {
+	// Distances holds the distance at which each level beyond full detail begins,
+	// in increasing order. Level i is used up to Distances[i].
+	Distances []float32
+
+	// Hysteresis is how far past a distance threshold a chunk has to move before its level
+	// changes, so that chunks close to a threshold do not flicker between levels.
+	Hysteresis float32
+}
+
+// DefaultLODSelector returns a selector that spreads the levels of detail evenly over the outer
+// half of the draw distance, given in chunks. Chunks at the edge of the draw distance are meshed at
+// the coarsest level.
+func DefaultLODSelector(chunkSize, drawDistance int) *LODSelector {
+	draw := float32(chunkSize * drawDistance)
+	step := draw / (2 * MaxLOD)
+	distances := make([]float32, MaxLOD)
+	for i := range distances {
+		distances[i] = draw/2 + float32(i)*step
+	}
+	return &LODSelector{
+		Distances:  distances,
+		Hysteresis: step / 4,
+	}
+}
+
+// Level returns the level of detail of a mesh at the given distance, given its current level
+func (s *LODSelector) Level(distance float32, current int) int {
+	level := current
+	if level > len(s.Distances) {
+		level = len(s.Distances)
+	}
+	for level < len(s.Distances) && distance > s.Distances[level]+s.Hysteresis {
+		level++
+	}
+	for level > 0 && distance < s.Distances[level-1]-s.Hysteresis {
+		level--
+	}
+	if level > MaxLOD {
+		level = MaxLOD
+	}
+	return level
+}
+
+// lodFactor returns the number of voxels merged along each axis at the given level of detail.
+// The factor is reduced until it evenly divides the chunk size.
+func lodFactor(chunk *Chunk, lod int) int {
+	factor := 1 << uint(lod)
+	for factor > 1 && (chunk.Sx%factor != 0 || chunk.Sy%factor != 0 || chunk.Sz%factor != 0) {
+		factor /= 2
+	}
+	return factor
+}
+
+// downsample returns a copy of the chunk at a lower resolution, where each voxel covers a cube of
+// factor^3 voxels of the original chunk. The downsampling is conservative: a voxel is opaque if any
+// voxel in its cube is opaque, and empty only if the entire cube is empty. Colors and light are
+// averaged over the voxels of the cube.
+//
+// Since downsampled voxels are never less opaque than the voxels they cover, full detail meshes of
+// neighboring chunks always have faces wherever a downsampled chunk is see-through.
+func downsample(chunk *Chunk, factor int) *Chunk {
+	size := chunk.Sx / factor
+	coarse := NewChunk(size, chunk.Seed, chunk.Cx, chunk.Cy, chunk.Cz)
+
+	type blockCount struct {
+		count   int
+		r, g, b int
+	}
+
+	for cz := 0; cz < size; cz++ {
+		for cx := 0; cx < size; cx++ {
+			for cy := 0; cy < size; cy++ {
+				counts := map[BlockID]*blockCount{}
+				opaque := false
+				light, sky, open := [3]int{}, float32(0), 0
+				for z := cz * factor; z < (cz+1)*factor; z++ {
+					for x := cx * factor; x < (cx+1)*factor; x++ {
+						for y := cy * factor; y < (cy+1)*factor; y++ {
+							v := chunk.At(x, y, z)
+							if !v.Type().Opaque {
+								lv := chunk.Light.Get(x, y, z)
+								sky += lv.V
+								for c := range light {
+									light[c] += int(lv.Color[c])
+								}
+								open++
+							}
+							if v.Block == AirBlock {
+								continue
+							}
+							opaque = opaque || v.Type().Opaque
+							bc, exists := counts[v.Block]
+							if !exists {
+								bc = &blockCount{}
+								counts[v.Block] = bc
+							}
+							bc.count++
+							bc.r += int(v.R)
+							bc.g += int(v.G)
+							bc.b += int(v.B)
+						}
+					}
+				}
+
+				// pick the most common block, preferring opaque blocks.
+				// ties are broken by block id, so that the result is deterministic
+				var best BlockID
+				var bestCount *blockCount
+				for id, bc := range counts {
+					if opaque && !Block(id).Opaque {
+						continue
+					}
+					if bestCount == nil || bc.count > bestCount.count || (bc.count == bestCount.count && id < best) {
+						best, bestCount = id, bc
+					}
+				}
+				if bestCount != nil {
+					coarse.Set(cx, cy, cz, Voxel{
+						Block: best,
+						R:     byte(bestCount.r / bestCount.count),
+						G:     byte(bestCount.g / bestCount.count),
+						B:     byte(bestCount.b / bestCount.count),
+					})
+				}
+
+				if open > 0 {
+					lv := coarse.Light.Get(cx, cy, cz)
+					lv.V = sky / float32(open)
+					for c := range light {
+						lv.Color[c] = byte(light[c] / open)
+					}
+				}
+			}
+		}
+	}
+
+	// the light volume holds an extra layer of sky light above the chunk
+	for cz := 0; cz < size; cz++ {
+		for cx := 0; cx < size; cx++ {
+			sky := float32(0)
+			for z := cz * factor; z < (cz+1)*factor; z++ {
+				for x := cx * factor; x < (cx+1)*factor; x++ {
+					sky += chunk.Light.Get(x, chunk.Sy, z).V
+				}
+			}
+			coarse.Light.Get(cx, size, cz).V = sky / float32(factor*factor)
+		}
+	}
+
+	return coarse
+}
+
+// scaleVertices scales the positions of downsampled mesh vertices back up to chunk coordinates
+func scaleVertices(vertices []VoxelVertex, factor int) []VoxelVertex {
+	if factor == 1 {
+		return vertices
+	}
+	for i := range vertices {
+		v := &vertices[i]
+		v.X = byte(int(v.X) * factor)
+		v.Y = byte(int(v.Y) * factor)
+		v.Z = byte(int(v.Z) * factor)
+	}
+	return vertices
+}
@@ -0,0 +1,162 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestLODSelector(t *testing.T) {
+	selector := &LODSelector{Distances: []float32{100, 200, 400}, Hysteresis: 10}
+	cases := []struct {
+		Distance float32
+		Current  int
+		Expected int
+	}{
+		{50, 0, 0},
+		{105, 0, 0},
+		{115, 0, 1},
+		{95, 1, 1},
+		{85, 1, 0},
+		{1000, 0, 3},
+		{50, 3, 0},
+		{205, 3, 2},
+	}
+	for _, c := range cases {
+		if level := selector.Level(c.Distance, c.Current); level != c.Expected {
+			t.Errorf("expected level %d at distance %f from level %d, got %d", c.Expected, c.Distance, c.Current, level)
+		}
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	chunk := NewChunk(4, 0, 0, 0, 0)
+	water := Voxel{Block: WaterBlock, B: 200}
+
+	// a single stone voxel in a cube of water makes the downsampled voxel opaque
+	for i := 0; i < 8; i++ {
+		chunk.Set(i&1, i>>1&1, i>>2&1, water)
+	}
+	chunk.Set(1, 1, 1, Voxel{Block: ColorBlock, R: 100})
+
+	// water alone stays water
+	chunk.Set(2, 0, 0, water)
+
+	// colors are averaged
+	chunk.Set(0, 2, 0, Voxel{Block: ColorBlock, R: 100, G: 10})
+	chunk.Set(1, 2, 0, Voxel{Block: ColorBlock, R: 200, G: 30})
+
+	coarse := downsample(chunk, 2)
+	if coarse.Sx != 2 {
+		t.Fatalf("expected a chunk of size 2, got %d", coarse.Sx)
+	}
+	expected := map[WorldPos]Voxel{
+		{0, 0, 0}: {Block: ColorBlock, R: 100},
+		{1, 0, 0}: water,
+		{0, 1, 0}: {Block: ColorBlock, R: 150, G: 20},
+		{1, 1, 1}: EmptyVoxel,
+	}
+	for p, v := range expected {
+		if actual := coarse.At(p.X, p.Y, p.Z); actual != v {
+			t.Errorf("expected %+v at %v, got %+v", v, p, actual)
+		}
+	}
+}
+
+func TestDownsampleConservative(t *testing.T) {
+	chunk := testWorldgen(t, 31481234, 16).Chunk(0, 0, 0)
+	for _, factor := range []int{2, 4, 8} {
+		coarse := downsample(chunk, factor)
+		for z := 0; z < chunk.Sz; z++ {
+			for x := 0; x < chunk.Sx; x++ {
+				for y := 0; y < chunk.Sy; y++ {
+					fine, v := chunk.At(x, y, z), coarse.At(x/factor, y/factor, z/factor)
+					if fine.Type().Opaque && !v.Type().Opaque {
+						t.Fatalf("factor %d: expected the voxel covering opaque %d,%d,%d to be opaque", factor, x, y, z)
+					}
+					if fine != EmptyVoxel && v == EmptyVoxel {
+						t.Fatalf("factor %d: expected the voxel covering %d,%d,%d not to be empty", factor, x, y, z)
+					}
+				}
+			}
+		}
+	}
+}
+
+// borderFaces returns the unit faces of a mesh lying in the plane x = plane, facing in the given direction
+func borderFaces(t *testing.T, data []VoxelVertex, n byte, plane int) map[[2]int]bool {
+	faces := map[[2]int]bool{}
+	for key := range rasterizeFaces(t, data) {
+		if key.N == n && key.Plane == plane {
+			faces[[2]int{key.V, key.U}] = true
+		}
+	}
+	return faces
+}
+
+func TestLODSeams(t *testing.T) {
+	size := 16
+	world := newWorld(31481234, size, testWorldgen(t, 31481234, size))
+	world.Store = newMemoryStore()
+	a, err := world.AddChunk(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := world.AddChunk(1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mesh := func(chunk *Chunk, lod int) []VoxelVertex {
+		return computeVertexData(NewNeighborhood(chunk, world), MeshGreedy, lod).Opaque
+	}
+
+	// every level pairing must cover the border plane wherever one side is opaque and the other is not
+	for _, lods := range [][2]int{{0, 2}, {2, 0}, {1, 3}, {3, 3}} {
+		fa, fb := lodFactor(a, lods[0]), lodFactor(b, lods[1])
+		ca, cb := downsample(a, fa), downsample(b, fb)
+
+		// faces of a point towards b along +x, faces of b point towards a along -x
+		facesA := borderFaces(t, mesh(a, lods[0]), 1, size)
+		facesB := borderFaces(t, mesh(b, lods[1]), 2, 0)
+
+		seams := 0
+		for y := 0; y < size; y++ {
+			for z := 0; z < size; z++ {
+				opaqueA := ca.At((size-1)/fa, y/fa, z/fa).Type().Opaque
+				opaqueB := cb.At(0, y/fb, z/fb).Type().Opaque
+				if opaqueA && !opaqueB {
+					seams++
+					if !facesA[[2]int{y, z}] {
+						t.Errorf("lod %v: expected a face on chunk a at y=%d z=%d", lods, y, z)
+					}
+				}
+				if opaqueB && !opaqueA {
+					seams++
+					if !facesB[[2]int{y, z}] {
+						t.Errorf("lod %v: expected a face on chunk b at y=%d z=%d", lods, y, z)
+					}
+				}
+			}
+		}
+		if lods[0] != lods[1] && seams == 0 {
+			t.Errorf("lod %v: expected the levels to differ along the border", lods)
+		}
+	}
+}
+
+func TestLODReducesVertices(t *testing.T) {
+	chunk := testWorldgen(t, 31481234, 16).Chunk(0, 0, 0)
+	previous := -1
+	for lod := 0; lod <= MaxLOD; lod++ {
+		data := computeVertexData(NewNeighborhood(chunk, nil), MeshGreedy, lod).Opaque
+		factor := byte(lodFactor(chunk, lod))
+		for _, v := range data {
+			if v.X%factor != 0 || v.Y%factor != 0 || v.Z%factor != 0 || v.X > 16 || v.Y > 16 || v.Z > 16 {
+				t.Fatalf("lod %d: vertex %d,%d,%d is not on the downsampled grid", lod, v.X, v.Y, v.Z)
+			}
+		}
+		if previous >= 0 && len(data) >= previous {
+			t.Errorf("lod %d: expected fewer vertices than %d, got %d", lod, previous, len(data))
+		}
+		previous = len(data)
+	}
+}
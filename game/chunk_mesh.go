@@ -3,20 +3,67 @@ package game
 import (
 	"github.com/johanhenriksson/goworld/assets"
 	"github.com/johanhenriksson/goworld/engine"
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
+	"github.com/johanhenriksson/goworld/render"
 )
 
+// MeshMode selects the algorithm used to generate chunk meshes
+type MeshMode int
+
+const (
+	// MeshSimple emits a separate quad for every exposed voxel face
+	MeshSimple MeshMode = iota
+
+	// MeshGreedy merges adjacent coplanar faces with identical color and occlusion into larger quads
+	MeshGreedy
+)
+
+// ChunkMesh draws a chunk. Opaque faces are drawn by the embedded mesh in the geometry pass,
+// while faces of translucent voxels are drawn by a second mesh in the forward pass.
 type ChunkMesh struct {
 	*engine.Mesh
 	*Chunk
-	meshComputed chan []VoxelVertex
+	Mode MeshMode
+
+	// Translucent holds the faces of translucent voxels, such as glass and water
+	Translucent *engine.Mesh
+
+	// Source provides access to neighboring chunks, so that faces and occlusion along
+	// the chunk borders match the surrounding terrain. If nil, the chunk is meshed in isolation.
+	Source ChunkSource
+
+	// LOD is the level of detail of the mesh, from 0 (full detail) to MaxLOD. Use SetLOD to change it.
+	LOD int
+
+	// Connectivity records which faces of the chunk are connected through empty space.
+	// It is computed along with the mesh, and connects every face until then.
+	Connectivity ChunkConnectivity
+
+	meshComputed chan chunkMeshData
+	invalid      bool
+	computing    bool
+	occluded     bool
+}
+
+// chunkMeshData holds the vertices of the opaque and translucent parts of a chunk mesh,
+// and the face connectivity of the chunk
+type chunkMeshData struct {
+	Opaque       []VoxelVertex
+	Translucent  []VoxelVertex
+	Connectivity ChunkConnectivity
 }
 
 func NewChunkMesh(chunk *Chunk) *ChunkMesh {
 	mesh := engine.NewMesh(assets.GetMaterialShared("color_voxels"))
+	translucent := engine.NewPrimitiveMesh(render.Triangles, render.Forward, assets.GetMaterialShared("color_voxels.f"))
 	chk := &ChunkMesh{
 		Mesh:         mesh,
 		Chunk:        chunk,
-		meshComputed: make(chan []VoxelVertex),
+		Mode:         MeshGreedy,
+		Translucent:  translucent,
+		Connectivity: FullConnectivity,
+		meshComputed: make(chan chunkMeshData, 1),
 	}
 	chk.Compute()
 	return chk
@@ -26,45 +73,153 @@ func (cm *ChunkMesh) Update(dt float32) {
 	cm.Mesh.Update(dt)
 	select {
 	case newMesh := <-cm.meshComputed:
-		cm.Buffer(newMesh)
+		cm.Buffer(newMesh.Opaque)
+		cm.Translucent.Buffer(newMesh.Translucent)
+		cm.Connectivity = newMesh.Connectivity
+		cm.computing = false
 	default:
 	}
+
+	// only one computation may run at a time, so that results arrive in order
+	if cm.invalid && !cm.computing {
+		cm.invalid = false
+		cm.computing = true
+		cm.computeAsync()
+	}
 }
 
-// Queues recomputation of the mesh
-func (cm *ChunkMesh) Compute() {
+// computeAsync computes the mesh on a background goroutine, and sends the result to meshComputed.
+// The mesh is computed from a snapshot of the chunk and the current mesh settings, since they may
+// be modified on the main thread in the meantime.
+func (cm *ChunkMesh) computeAsync() {
+	view := NewNeighborhood(cm.Chunk.Snapshot(), cm.Source)
+	mode, lod := cm.Mode, cm.LOD
 	go func() {
-		data := cm.computeVertexData()
-		cm.meshComputed <- data
+		cm.meshComputed <- computeVertexData(view, mode, lod)
 	}()
 }
 
-func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
-	data := make([]VoxelVertex, 0, 64)
-	light := cm.Light.Brightness
+// Queues recomputation of the mesh
+func (cm *ChunkMesh) Compute() {
+	cm.invalid = true
+}
+
+// SetLOD changes the level of detail of the mesh, and queues recomputation if it changed
+func (cm *ChunkMesh) SetLOD(lod int) {
+	if lod < 0 {
+		lod = 0
+	}
+	if lod > MaxLOD {
+		lod = MaxLOD
+	}
+	if lod != cm.LOD {
+		cm.LOD = lod
+		cm.Compute()
+	}
+}
+
+// DrawForward draws the translucent faces of the chunk. Depth writes are disabled, so that
+// translucent faces behind other translucent faces of the same chunk are not discarded.
+func (cm *ChunkMesh) DrawForward(args engine.DrawArgs) {
+	render.DepthOutput(false)
+	cm.Translucent.DrawForward(args)
+	render.DepthOutput(true)
+}
+
+// Center returns the center of the chunk in object space.
+// Used to sort translucent chunk meshes back to front.
+func (cm *ChunkMesh) Center() vec3.T {
+	return vec3.NewI(cm.Sx, cm.Sy, cm.Sz).Scaled(0.5)
+}
+
+// Bounds returns the bounding box of the chunk in object space.
+// Used to cull chunk meshes outside of the view frustum.
+func (cm *ChunkMesh) Bounds() (physics.Box, bool) {
+	return physics.Box{
+		Extents: vec3.NewI(cm.Sx, cm.Sy, cm.Sz),
+		Center:  cm.Center(),
+	}, true
+}
+
+// SetOccluded hides the chunk mesh from the camera, or shows it again
+func (cm *ChunkMesh) SetOccluded(occluded bool) {
+	cm.occluded = occluded
+}
+
+// Occluded returns true if the chunk can not be seen from the camera, such as a cave behind solid rock.
+// Occluded chunk meshes are skipped by camera passes, but still cast shadows.
+func (cm *ChunkMesh) Occluded() bool {
+	return cm.occluded
+}
+
+// Delete frees the GPU resources held by both meshes
+func (cm *ChunkMesh) Delete() {
+	cm.Mesh.Delete()
+	cm.Translucent.Delete()
+}
+
+// computeVertexData meshes the center chunk of a neighborhood with the given mode and level of detail
+func computeVertexData(view *Neighborhood, mode MeshMode, lod int) chunkMeshData {
+	// connectivity is always computed at full detail, since downsampled chunks are more opaque
+	connectivity := ComputeConnectivity(view.Center)
+
+	factor := lodFactor(view.Center, lod)
+	if factor > 1 {
+		// reduced detail meshes are computed from a downsampled copy of the chunk, meshed in
+		// isolation. this gives every solid voxel along the chunk borders a face, forming skirts
+		// that hide the seams against neighbors meshed at other levels of detail.
+		view = NewNeighborhood(downsample(view.Center, factor), nil)
+	}
+
+	quads := computeQuads(view)
+	bakeBlockLight(view, quads)
+	opaque, translucent := splitTranslucent(view, quads)
+	if mode == MeshGreedy {
+		opaque = mergeQuads(opaque)
+		translucent = mergeQuads(translucent)
+	}
+	return chunkMeshData{
+		Opaque:       scaleVertices(quadVertices(opaque), factor),
+		Translucent:  scaleVertices(quadVertices(translucent), factor),
+		Connectivity: connectivity,
+	}
+}
+
+// facing returns true if the face of a neighboring voxel is visible from a voxel that can be
+// seen through. Faces between voxels of the same type, such as two panes of glass, are hidden.
+func facing(v, neighbor Voxel) bool {
+	return neighbor.Block != AirBlock && neighbor.Block != v.Block
+}
+
+// computeQuads returns a quad for every exposed voxel face in the center chunk of the neighborhood.
+// Faces of voxels in neighboring chunks are left to their own chunk meshes.
+func computeQuads(view *Neighborhood) []voxelQuad {
+	quads := make([]voxelQuad, 0, 64)
+	light := view.Brightness
+	chunk := view.Center
 	Omax := float32(220)
 
-	for z := -1; z <= cm.Sz; z++ {
-		for x := -1; x <= cm.Sx; x++ {
-			for y := -1; y <= cm.Sy; y++ {
-				v := cm.At(x, y, z)
-				if v != EmptyVoxel {
-					// consider ONLY empty voxels
+	for z := -1; z <= chunk.Sz; z++ {
+		for x := -1; x <= chunk.Sx; x++ {
+			for y := -1; y <= chunk.Sy; y++ {
+				v := view.At(x, y, z)
+				if v.Type().Opaque {
+					// consider ONLY voxels that can be seen through
 					continue
 				}
 
-				xp := cm.At(x+1, y, z)
-				xn := cm.At(x-1, y, z)
-				yp := cm.At(x, y+1, z)
-				yn := cm.At(x, y-1, z)
-				zp := cm.At(x, y, z+1)
-				zn := cm.At(x, y, z-1)
-				xpf := xp != EmptyVoxel
-				xnf := xn != EmptyVoxel
-				ypf := yp != EmptyVoxel
-				ynf := yn != EmptyVoxel
-				zpf := zp != EmptyVoxel
-				znf := zn != EmptyVoxel
+				xp := view.At(x+1, y, z)
+				xn := view.At(x-1, y, z)
+				yp := view.At(x, y+1, z)
+				yn := view.At(x, y-1, z)
+				zp := view.At(x, y, z+1)
+				zn := view.At(x, y, z-1)
+				xpf := facing(v, xp)
+				xnf := facing(v, xn)
+				ypf := facing(v, yp)
+				ynf := facing(v, yn)
+				zpf := facing(v, zp)
+				znf := facing(v, zn)
 
 				l := light(x, y, z)
 
@@ -78,7 +233,7 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 					lynzn := light(x, y-1, z-1)
 					lynzp := light(x, y-1, z+1)
 
-					if xpf {
+					if xpf && chunk.Contains(x+1, y, z) {
 						// xp is empty - tesselate square with X- normal
 						n := byte(2)
 						v1 := VoxelVertex{
@@ -113,10 +268,10 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 						if ynf && znf {
 							v4.O = byte(Omax * (1 - (lzn+lyn+l)/3))
 						}
-						data = append(data, v2, v3, v1, v4, v3, v2)
+						quads = append(quads, voxelQuad{N: n, X: x, Y: y, Z: z, V: [4]VoxelVertex{v1, v2, v3, v4}})
 					}
 
-					if xnf {
+					if xnf && chunk.Contains(x-1, y, z) {
 						// xn is empty - tesselate square with x+ normal
 						n := byte(1)
 						v1 := VoxelVertex{
@@ -151,7 +306,7 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 						if ynf && zpf {
 							v4.O = byte(Omax * (1 - (lyn+lzp+l)/3))
 						}
-						data = append(data, v2, v3, v1, v4, v3, v2)
+						quads = append(quads, voxelQuad{N: n, X: x, Y: y, Z: z, V: [4]VoxelVertex{v1, v2, v3, v4}})
 					}
 				}
 
@@ -165,7 +320,7 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 					lxnzp := light(x-1, y, z+1)
 					lxnzn := light(x-1, y, z-1)
 
-					if ypf {
+					if ypf && chunk.Contains(x, y+1, z) {
 						n := byte(4) // YN
 						v1 := VoxelVertex{
 							X: byte(x + 1), Y: byte(y + 1), Z: byte(z + 1), N: n,
@@ -199,10 +354,10 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 						if xnf && znf {
 							v4.O = byte(Omax * (1 - (lxn+lzn+l)/3))
 						}
-						data = append(data, v1, v3, v2, v2, v3, v4)
+						quads = append(quads, voxelQuad{N: n, X: x, Y: y, Z: z, V: [4]VoxelVertex{v1, v2, v3, v4}})
 					}
 
-					if ynf {
+					if ynf && chunk.Contains(x, y-1, z) {
 						// Y-1 is filled, add quad with Y+ normal
 						n := byte(3) // YP
 						v1 := VoxelVertex{
@@ -237,7 +392,7 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 						if xnf && znf {
 							v4.O = byte(Omax * (1 - l/3))
 						}
-						data = append(data, v2, v3, v1, v4, v3, v2)
+						quads = append(quads, voxelQuad{N: n, X: x, Y: y, Z: z, V: [4]VoxelVertex{v1, v2, v3, v4}})
 					}
 				}
 
@@ -251,7 +406,7 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 					lxnyn := light(x-1, y-1, z)
 					lxpyn := light(x+1, y-1, z)
 
-					if zpf {
+					if zpf && chunk.Contains(x, y, z+1) {
 						// zp is empty - tesselate square with ZN normal
 						n := byte(6)
 						v1 := VoxelVertex{
@@ -286,10 +441,10 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 						if xpf && ynf {
 							v4.O = byte(Omax * (1 - (lxp+lyn+l)/3))
 						}
-						data = append(data, v2, v3, v1, v4, v3, v2)
+						quads = append(quads, voxelQuad{N: n, X: x, Y: y, Z: z, V: [4]VoxelVertex{v1, v2, v3, v4}})
 					}
 
-					if znf {
+					if znf && chunk.Contains(x, y, z-1) {
 						// zn is empty - tesselate square with ZP normal
 						n := byte(5)
 						v1 := VoxelVertex{
@@ -324,11 +479,11 @@ func (cm *ChunkMesh) computeVertexData() []VoxelVertex {
 						if xpf && ynf {
 							v4.O = byte(Omax * (1 - (lxp+lyn+l)/3))
 						}
-						data = append(data, v1, v3, v2, v2, v3, v4)
+						quads = append(quads, voxelQuad{N: n, X: x, Y: y, Z: z, V: [4]VoxelVertex{v1, v2, v3, v4}})
 					}
 				}
 			}
 		}
 	}
-	return data
+	return quads
 }
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// testChunkMesh creates a chunk mesh without GPU buffers, so that it can be computed in tests
+func testChunkMesh(chunk *Chunk, source ChunkSource) *ChunkMesh {
+	return &ChunkMesh{
+		Chunk:        chunk,
+		Source:       source,
+		Mode:         MeshGreedy,
+		meshComputed: make(chan chunkMeshData, 1),
+	}
+}
+
+// TestChunkMeshConcurrentEdits computes meshes in the background while voxels are modified on
+// the calling goroutine. Run with -race to detect unsynchronized access to chunk data.
+func TestChunkMeshConcurrentEdits(t *testing.T) {
+	// the mesher must run in parallel with the edits, even on a single core
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	rnd := rand.New(rand.NewSource(1))
+	chunk := randomChunk(8, 1)
+	cm := testChunkMesh(chunk, nil)
+	voxels := Voxels{
+		EmptyVoxel,
+		{Block: ColorBlock, R: 200},
+		{Block: GlassBlock, B: 200},
+		{Block: LampBlock, R: 255, G: 180, B: 60},
+	}
+
+	for i := 0; i < 20; i++ {
+		cm.computeAsync()
+		for done := false; !done; {
+			select {
+			case <-cm.meshComputed:
+				done = true
+			default:
+				chunk.Set(rnd.Intn(8), rnd.Intn(8), rnd.Intn(8), voxels[rnd.Intn(len(voxels))])
+			}
+		}
+	}
+}
+
+// TestChunkMeshConcurrentNeighborEdits computes meshes in the background while the voxels bordering
+// the meshed chunk are modified. Run with -race to detect unsynchronized access to neighboring chunks.
+func TestChunkMeshConcurrentNeighborEdits(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	rnd := rand.New(rand.NewSource(1))
+	world := testWorld(newMemoryStore())
+	for _, cp := range ChunksInRadius(ChunkPos{}, 1) {
+		world.AddChunk(cp.X, cp.Y, cp.Z)
+	}
+	center := world.Chunk(ChunkPos{})
+	cm := testChunkMesh(center, world)
+	voxels := Voxels{
+		EmptyVoxel,
+		{Block: ColorBlock, R: 200},
+		{Block: LampBlock, R: 255, G: 180, B: 60},
+	}
+
+	// positions just outside the center chunk, within the border of a face neighbor
+	size := world.ChunkSize
+	border := func() (int, int, int) {
+		p := [3]int{rnd.Intn(size), rnd.Intn(size), rnd.Intn(size)}
+		p[rnd.Intn(3)] = []int{-1, size}[rnd.Intn(2)]
+		return p[0], p[1], p[2]
+	}
+
+	for i := 0; i < 20; i++ {
+		cm.computeAsync()
+		for done := false; !done; {
+			select {
+			case <-cm.meshComputed:
+				done = true
+			default:
+				x, y, z := border()
+				world.Set(x, y, z, voxels[rnd.Intn(len(voxels))])
+			}
+		}
+	}
+}
+
+// TestChunkMeshConcurrentLOD computes meshes in the background while the level of detail changes,
+// as the streamer does every frame. Run with -race to detect unsynchronized access to the mesh settings.
+func TestChunkMeshConcurrentLOD(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	cm := testChunkMesh(randomChunk(8, 1), nil)
+	for i := 0; i < 20; i++ {
+		cm.computeAsync()
+		for done, lod := false, 0; !done; lod++ {
+			select {
+			case <-cm.meshComputed:
+				done = true
+			default:
+				cm.SetLOD(lod % (MaxLOD + 1))
+			}
+		}
+	}
+}
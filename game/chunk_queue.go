@@ -0,0 +1,110 @@
+package game
+
+import (
+	"sort"
+	"sync"
+)
+
+// DistanceSqr returns the squared distance between two chunk positions, in chunks
+func (c ChunkPos) DistanceSqr(o ChunkPos) int {
+	dx, dy, dz := c.X-o.X, c.Y-o.Y, c.Z-o.Z
+	return dx*dx + dy*dy + dz*dz
+}
+
+// ChunksInRadius returns all chunk positions within the given radius of a center chunk,
+// ordered from the nearest to the furthest.
+func ChunksInRadius(center ChunkPos, radius int) []ChunkPos {
+	positions := make([]ChunkPos, 0, 4*radius*radius*radius)
+	for z := -radius; z <= radius; z++ {
+		for y := -radius; y <= radius; y++ {
+			for x := -radius; x <= radius; x++ {
+				if x*x+y*y+z*z > radius*radius {
+					continue
+				}
+				positions = append(positions, ChunkPos{center.X + x, center.Y + y, center.Z + z})
+			}
+		}
+	}
+	sortByDistance(center, positions)
+	return positions
+}
+
+// sortByDistance sorts chunk positions by their distance to a center chunk
+func sortByDistance(center ChunkPos, positions []ChunkPos) {
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].DistanceSqr(center) < positions[j].DistanceSqr(center)
+	})
+}
+
+// chunkQueue is a blocking work queue of chunk positions, handed out in order.
+// Positions that have been handed out remain active until they are marked as done.
+type chunkQueue struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	items  []ChunkPos
+	active map[ChunkPos]bool
+	closed bool
+}
+
+func newChunkQueue() *chunkQueue {
+	q := &chunkQueue{
+		active: make(map[ChunkPos]bool),
+	}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+// Reset replaces all queued positions. Active positions are not affected.
+func (q *chunkQueue) Reset(items []ChunkPos) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.items = items
+	q.cond.Broadcast()
+}
+
+// Pop blocks until a position is available and marks it as active.
+// Returns false once the queue is closed.
+func (q *chunkQueue) Pop() (ChunkPos, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return ChunkPos{}, false
+	}
+	cp := q.items[0]
+	q.items = q.items[1:]
+	q.active[cp] = true
+	return cp, true
+}
+
+// Active returns true if the position has been handed out but not yet marked as done
+func (q *chunkQueue) Active(cp ChunkPos) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.active[cp]
+}
+
+// Done marks an active position as finished
+func (q *chunkQueue) Done(cp ChunkPos) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.active, cp)
+}
+
+// Len returns the number of queued positions
+func (q *chunkQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.items)
+}
+
+// Close the queue, releasing any blocked workers
+func (q *chunkQueue) Close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	q.items = nil
+	q.cond.Broadcast()
+}
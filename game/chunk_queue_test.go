@@ -0,0 +1,68 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestChunksInRadius(t *testing.T) {
+	center := ChunkPos{-3, 1, 2}
+	positions := ChunksInRadius(center, 2)
+
+	if positions[0] != center {
+		t.Errorf("expected center chunk first, was %v", positions[0])
+	}
+	seen := map[ChunkPos]bool{}
+	last := 0
+	for _, cp := range positions {
+		d := cp.DistanceSqr(center)
+		if d > 4 {
+			t.Errorf("chunk %v is outside the radius", cp)
+		}
+		if d < last {
+			t.Errorf("chunk %v is out of order", cp)
+		}
+		if seen[cp] {
+			t.Errorf("chunk %v appears twice", cp)
+		}
+		seen[cp] = true
+		last = d
+	}
+	// 1 center + 6 at distance 1 + 12 at sqrt(2) + 8 at sqrt(3) + 6 at 2
+	if len(positions) != 33 {
+		t.Errorf("expected 33 chunks within radius 2, found %d", len(positions))
+	}
+}
+
+func TestChunkQueue(t *testing.T) {
+	q := newChunkQueue()
+	q.Reset([]ChunkPos{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}})
+
+	cp, ok := q.Pop()
+	if !ok || cp != (ChunkPos{0, 0, 0}) {
+		t.Fatalf("expected first queued chunk, got %v", cp)
+	}
+	if !q.Active(cp) {
+		t.Error("expected popped chunk to be active")
+	}
+
+	// replanning replaces queued items, but keeps active ones
+	q.Reset([]ChunkPos{{5, 0, 0}})
+	if q.Len() != 1 || !q.Active(cp) {
+		t.Error("expected reset to replace the queue")
+	}
+	q.Done(cp)
+	if q.Active(cp) {
+		t.Error("expected chunk to be inactive once done")
+	}
+
+	done := make(chan bool)
+	go func() {
+		q.Pop()
+		_, ok := q.Pop()
+		done <- ok
+	}()
+	q.Close()
+	if ok := <-done; ok {
+		t.Error("expected pop to fail once the queue is closed")
+	}
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QuarantineDir is the name of the directory within a store where corrupt chunk data is kept
+const QuarantineDir = "quarantine"
+
+// ChunkStore is a persistent storage backend for chunks
+type ChunkStore interface {
+	// Load the chunk at the given chunk coordinates.
+	// Returns an error satisfying os.IsNotExist if the chunk has never been stored.
+	// Corrupt chunk data is moved to quarantine before an error wrapping ErrCorruptChunk is returned,
+	// so that it is not lost when the chunk is saved again. Chunks written in a newer format result in
+	// an error wrapping ErrUnsupportedVersion, and are left in place.
+	Load(cx, cy, cz int) (*Chunk, error)
+
+	// Save a chunk
+	Save(chunk *Chunk) error
+
+	// SaveAll saves several chunks at once, allowing the store to batch its writes.
+	// Returns one error per chunk, which is nil if the chunk was saved.
+	SaveAll(chunks []*Chunk) []error
+}
+
+// FileStore keeps each chunk in a separate file
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore creates a chunk store that keeps one file per chunk in the given directory
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		Path: path,
+	}
+}
+
+// Load a chunk from its file
+func (fs *FileStore) Load(cx, cy, cz int) (*Chunk, error) {
+	chunk, err := LoadChunk(fs.Path, cx, cy, cz)
+	if errors.Is(err, ErrCorruptChunk) {
+		name := findChunkFile(fs.Path, cx, cy, cz)
+		if qerr := quarantineFile(fs.Path, name); qerr != nil {
+			return nil, fmt.Errorf("%w (quarantine failed: %s)", err, qerr)
+		}
+	}
+	return chunk, err
+}
+
+// Save a chunk to its file
+func (fs *FileStore) Save(chunk *Chunk) error {
+	if err := os.MkdirAll(fs.Path, 0755); err != nil {
+		return err
+	}
+	return chunk.Write(fs.Path)
+}
+
+// SaveAll saves each chunk to its own file
+func (fs *FileStore) SaveAll(chunks []*Chunk) []error {
+	errs := make([]error, len(chunks))
+	for i, chunk := range chunks {
+		errs[i] = fs.Save(chunk)
+	}
+	return errs
+}
+
+// quarantineName returns a unique file name in the quarantine directory of a store
+func quarantineName(path, name string) string {
+	stamp := time.Now().Format("20060102-150405.000000000")
+	return filepath.Join(path, QuarantineDir, fmt.Sprintf("%s.%s", filepath.Base(name), stamp))
+}
+
+// quarantineFile moves a file into the quarantine directory of a store
+func quarantineFile(path, name string) error {
+	if err := os.MkdirAll(filepath.Join(path, QuarantineDir), 0755); err != nil {
+		return err
+	}
+	return os.Rename(name, quarantineName(path, name))
+}
+
+// quarantineData writes data into the quarantine directory of a store
+func quarantineData(path, name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(path, QuarantineDir), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(quarantineName(path, name), data, 0644)
+}
+
+// ConvertChunkDir copies every chunk file in the given directory into another chunk store.
+// Returns the number of chunks converted.
+func ConvertChunkDir(path string, store ChunkStore) (int, error) {
+	files, err := filepath.Glob(filepath.Join(path, "c_*.bin"))
+	if err != nil {
+		return 0, err
+	}
+
+	converted := 0
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return converted, err
+		}
+		chunk, err := decodeChunk(file)
+		file.Close()
+		if err != nil {
+			return converted, fmt.Errorf("error reading chunk file %s: %w", name, err)
+		}
+
+		if err := store.Save(chunk); err != nil {
+			return converted, fmt.Errorf("error converting chunk %d,%d,%d: %w", chunk.Cx, chunk.Cy, chunk.Cz, err)
+		}
+		converted++
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,268 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/johanhenriksson/goworld/engine/object"
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// ChunkStreamer keeps the chunks surrounding a focus position loaded and meshed.
+// Chunks within the worlds KeepDistance are loaded or generated by a pool of
+// background workers, nearest first. Chunks within the DrawDistance are meshed,
+// and chunks outside the keep distance are unloaded again.
+// Only chunks loaded by the streamer are managed by it.
+type ChunkStreamer struct {
+	*object.T
+	World *World
+
+	// LOD picks the level of detail of chunk meshes from their distance to the focus.
+	// Defaults to a selector for the draw distance of the world at the time the streamer is created.
+	// If nil, all chunks are meshed at full detail.
+	LOD *LODSelector
+
+	// Occlusion hides chunk meshes that can not be seen from the chunk containing the focus,
+	// such as caves behind solid rock. Requires the focus to be the camera position.
+	Occlusion bool
+
+	focus   vec3.T
+	center  ChunkPos
+	planned bool
+	chunks  map[ChunkPos]*streamedChunk
+	queue   *chunkQueue
+	results chan streamResult
+	done    chan struct{}
+	workers sync.WaitGroup
+
+	// meshes is false if chunks should be streamed without creating meshes, which require a GL context
+	meshes bool
+}
+
+// streamedChunk is a chunk loaded by the streamer, and its mesh if it is within draw distance
+type streamedChunk struct {
+	chunk  *Chunk
+	mesh   *ChunkMesh
+	object *object.T
+}
+
+// streamResult is a chunk loaded by a worker, or the error that prevented it from loading
+type streamResult struct {
+	Position ChunkPos
+	Chunk    *Chunk
+	Err      error
+}
+
+// NewChunkStreamer creates a chunk streamer for the given world, and starts its workers.
+func NewChunkStreamer(world *World, workers int) *ChunkStreamer {
+	s := &ChunkStreamer{
+		T:         object.New("ChunkStreamer"),
+		World:     world,
+		LOD:       DefaultLODSelector(world.ChunkSize, world.DrawDistance),
+		Occlusion: true,
+		chunks:    make(map[ChunkPos]*streamedChunk),
+		queue:     newChunkQueue(),
+		results:   make(chan streamResult, 4*workers),
+		done:      make(chan struct{}),
+		meshes:    true,
+	}
+	s.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	world.Subscribe(s.onChunkEvent)
+	return s
+}
+
+// onChunkEvent remeshes streamed chunks affected by changes to the world
+func (s *ChunkStreamer) onChunkEvent(event ChunkEvent) {
+	for _, cp := range event.Touched {
+		if entry, exists := s.chunks[cp]; exists && entry.mesh != nil {
+			entry.mesh.Compute()
+		}
+	}
+}
+
+// work loads chunks from the queue until the streamer is stopped
+func (s *ChunkStreamer) work() {
+	defer s.workers.Done()
+	for {
+		cp, ok := s.queue.Pop()
+		if !ok {
+			return
+		}
+		chunk, err := s.World.FetchChunk(cp.X, cp.Y, cp.Z)
+		select {
+		case s.results <- streamResult{Position: cp, Chunk: chunk, Err: err}:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// SetFocus sets the position around which chunks are streamed, usually the players position.
+func (s *ChunkStreamer) SetFocus(position vec3.T) {
+	s.focus = position
+}
+
+// Loaded returns the number of chunks currently loaded by the streamer
+func (s *ChunkStreamer) Loaded() int {
+	return len(s.chunks)
+}
+
+// Pending returns the number of chunks waiting to be loaded
+func (s *ChunkStreamer) Pending() int {
+	return s.queue.Len()
+}
+
+// Stop the background workers, and wait for them to exit.
+// Chunks loaded but not yet received are discarded.
+func (s *ChunkStreamer) Stop() {
+	s.queue.Close()
+	close(s.done)
+	s.workers.Wait()
+}
+
+// Update the streamer. Receives loaded chunks and replans if the focus moved to another chunk.
+func (s *ChunkStreamer) Update(dt float32) {
+	s.T.Update(dt)
+
+	center := WorldPosAt(s.focus).Chunk(s.World.ChunkSize)
+	if !s.planned || center != s.center {
+		s.center = center
+		s.planned = true
+		s.plan()
+	}
+
+	s.receive()
+	s.updateLOD()
+	s.updateOcclusion()
+}
+
+// updateLOD updates the level of detail of every streamed chunk mesh
+func (s *ChunkStreamer) updateLOD() {
+	if s.LOD == nil {
+		return
+	}
+	half := float32(s.World.ChunkSize) / 2
+	for cp, entry := range s.chunks {
+		if entry.mesh == nil {
+			continue
+		}
+		center := cp.Origin(s.World.ChunkSize).Vec3().Add(vec3.New(half, half, half))
+		distance := center.Sub(s.focus).Length()
+		entry.mesh.SetLOD(s.LOD.Level(distance, entry.mesh.LOD))
+	}
+}
+
+// updateOcclusion hides the meshes of chunks that are not visible from the chunk containing the focus
+func (s *ChunkStreamer) updateOcclusion() {
+	var visible map[ChunkPos]bool
+	if s.Occlusion {
+		visible = VisibleChunks(s.center, s.connectivity)
+	}
+	for cp, entry := range s.chunks {
+		if entry.mesh != nil {
+			entry.mesh.SetOccluded(s.Occlusion && !visible[cp])
+		}
+	}
+}
+
+// connectivity returns the face connectivity of a loaded chunk. Chunks without a streamed mesh
+// are assumed to be see-through.
+func (s *ChunkStreamer) connectivity(cp ChunkPos) (ChunkConnectivity, bool) {
+	if entry, exists := s.chunks[cp]; exists && entry.mesh != nil {
+		return entry.mesh.Connectivity, true
+	}
+	if s.World.Chunk(cp) != nil {
+		return FullConnectivity, true
+	}
+	return 0, false
+}
+
+// receive inserts chunks loaded by the workers into the world
+func (s *ChunkStreamer) receive() {
+	for {
+		select {
+		case result := <-s.results:
+			cp, chunk := result.Position, result.Chunk
+			s.queue.Done(cp)
+
+			// chunks that failed to load are left out, and retried the next time the streamer plans
+			if result.Err != nil {
+				fmt.Println(result.Err)
+				continue
+			}
+
+			// skip chunks that are no longer wanted, or were loaded by someone else in the meantime
+			if !s.withinKeep(cp) || s.World.Chunk(cp) != nil {
+				continue
+			}
+
+			s.World.Insert(chunk)
+			entry := &streamedChunk{chunk: chunk}
+			s.chunks[cp] = entry
+			s.updateMesh(cp, entry)
+		default:
+			return
+		}
+	}
+}
+
+// plan unloads chunks that are out of range, updates meshes and queues missing chunks nearest first.
+func (s *ChunkStreamer) plan() {
+	for cp, entry := range s.chunks {
+		if !s.withinKeep(cp) {
+			s.unload(cp, entry)
+			continue
+		}
+		s.updateMesh(cp, entry)
+	}
+
+	wanted := ChunksInRadius(s.center, s.World.KeepDistance)
+	missing := make([]ChunkPos, 0, len(wanted))
+	for _, cp := range wanted {
+		if s.World.Chunk(cp) != nil || s.queue.Active(cp) {
+			continue
+		}
+		missing = append(missing, cp)
+	}
+	s.queue.Reset(missing)
+}
+
+// updateMesh creates or destroys the mesh of a streamed chunk depending on its distance to the focus
+func (s *ChunkStreamer) updateMesh(cp ChunkPos, entry *streamedChunk) {
+	visible := s.meshes && cp.DistanceSqr(s.center) <= s.World.DrawDistance*s.World.DrawDistance
+	if visible && entry.mesh == nil {
+		entry.mesh = NewChunkMesh(entry.chunk)
+		entry.mesh.Source = s.World
+		entry.object = object.New(fmt.Sprintf("Chunk %d,%d,%d", cp.X, cp.Y, cp.Z), entry.mesh)
+		entry.object.SetPosition(cp.Origin(s.World.ChunkSize).Vec3())
+		s.Attach(entry.object)
+	}
+	if !visible && entry.mesh != nil {
+		s.destroyMesh(entry)
+	}
+}
+
+func (s *ChunkStreamer) destroyMesh(entry *streamedChunk) {
+	s.Detach(entry.object)
+	entry.mesh.Delete()
+	entry.mesh = nil
+	entry.object = nil
+}
+
+// unload a streamed chunk and remove it from the world
+func (s *ChunkStreamer) unload(cp ChunkPos, entry *streamedChunk) {
+	if entry.mesh != nil {
+		s.destroyMesh(entry)
+	}
+	// if saving fails, the chunk stays queued in the world and the
+	// error is reported by the next flush
+	s.World.Unload(cp)
+	delete(s.chunks, cp)
+}
+
+func (s *ChunkStreamer) withinKeep(cp ChunkPos) bool {
+	return cp.DistanceSqr(s.center) <= s.World.KeepDistance*s.World.KeepDistance
+}
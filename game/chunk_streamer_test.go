@@ -0,0 +1,132 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// testStreamer creates a streamer that loads chunks without meshing them
+func testStreamer(world *World, workers int) *ChunkStreamer {
+	s := NewChunkStreamer(world, workers)
+	s.meshes = false
+	return s
+}
+
+// streamUntil updates the streamer until every chunk within the keep distance of the focus is loaded
+func streamUntil(t *testing.T, s *ChunkStreamer, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	s.Update(0.016)
+	for s.Loaded() != expected || s.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d streamed chunks, got %d with %d pending", expected, s.Loaded(), s.Pending())
+		}
+		time.Sleep(time.Millisecond)
+		s.Update(0.016)
+	}
+}
+
+func TestChunkStreamerCycle(t *testing.T) {
+	store := newMemoryStore()
+	world := testWorld(store)
+	world.KeepDistance = 1
+	s := testStreamer(world, 2)
+	defer s.Stop()
+
+	// a keep distance of one chunk covers the focus chunk and its six face neighbors
+	streamUntil(t, s, 7)
+	for _, cp := range ChunksInRadius(ChunkPos{}, 1) {
+		if world.Chunk(cp) == nil {
+			t.Errorf("expected chunk %v to be loaded", cp)
+		}
+	}
+
+	// moving the focus unloads the chunks behind it, saving modified chunks on the way out
+	world.Set(1, 1, 1, Voxel{Block: ColorBlock, R: 255})
+	s.SetFocus(vec3.NewI(3*world.ChunkSize, 0, 0))
+	s.Update(0.016)
+	for _, cp := range ChunksInRadius(ChunkPos{}, 1) {
+		if world.Chunk(cp) != nil {
+			t.Errorf("expected chunk %v to be unloaded", cp)
+		}
+	}
+	saved, exists := store.chunks[ChunkPos{}]
+	if !exists {
+		t.Fatal("expected modified chunk to be saved when unloaded")
+	}
+	if v := saved.At(1, 1, 1); v.Block != ColorBlock {
+		t.Errorf("expected saved chunk to hold the modified voxel, got %v", v)
+	}
+
+	streamUntil(t, s, 7)
+	for _, cp := range ChunksInRadius(ChunkPos{3, 0, 0}, 1) {
+		if world.Chunk(cp) == nil {
+			t.Errorf("expected chunk %v to be loaded", cp)
+		}
+	}
+
+	// returning loads the modified chunk back from the store
+	s.SetFocus(vec3.Zero)
+	streamUntil(t, s, 7)
+	if v := world.Voxel(1, 1, 1); v.Block != ColorBlock {
+		t.Errorf("expected modified voxel to be loaded from the store, got %v", v)
+	}
+}
+
+func TestChunkStreamerStop(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	world.KeepDistance = 3
+	s := testStreamer(world, 1)
+
+	// plan without receiving, so that the worker fills the result buffer and blocks
+	s.Update(0.016)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.results) < cap(s.results) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected stop to return while the workers are blocked on results")
+	}
+}
+
+func TestChunkStreamerLOD(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	world.KeepDistance = world.DrawDistance
+	s := testStreamer(world, 2)
+	defer s.Stop()
+
+	// focus on the center of the origin chunk
+	half := float32(world.ChunkSize) / 2
+	s.SetFocus(vec3.New(half, half, half))
+	streamUntil(t, s, len(ChunksInRadius(ChunkPos{}, world.KeepDistance)))
+
+	// attach meshes to every chunk within draw distance, as the streamer would with a GL context
+	for cp, entry := range s.chunks {
+		if cp.DistanceSqr(ChunkPos{}) <= world.DrawDistance*world.DrawDistance {
+			entry.mesh = testChunkMesh(entry.chunk, world)
+		}
+	}
+	s.updateLOD()
+
+	cases := map[ChunkPos]int{
+		{0, 0, 0}:                   0,
+		{1, 0, 0}:                   0,
+		{0, 2, 0}:                   1,
+		{0, 0, -world.DrawDistance}: MaxLOD,
+	}
+	for cp, lod := range cases {
+		if level := s.chunks[cp].mesh.LOD; level != lod {
+			t.Errorf("expected chunk %v to be meshed at level %d, was %d", cp, lod, level)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package game
+
+// ChunkFace identifies one of the six faces of a chunk
+type ChunkFace int
+
+const (
+	FaceWest  ChunkFace = iota // -x
+	FaceEast                   // +x
+	FaceDown                   // -y
+	FaceUp                     // +y
+	FaceNorth                  // -z
+	FaceSouth                  // +z
+)
+
+// chunkFaces lists every chunk face, in the same order as lightDirections
+var chunkFaces = [6]ChunkFace{FaceWest, FaceEast, FaceDown, FaceUp, FaceNorth, FaceSouth}
+
+// Opposite returns the face on the other side of the chunk
+func (f ChunkFace) Opposite() ChunkFace {
+	return f ^ 1
+}
+
+// Neighbor returns the position of the chunk adjacent to the given chunk across this face
+func (f ChunkFace) Neighbor(cp ChunkPos) ChunkPos {
+	d := lightDirections[f]
+	return ChunkPos{cp.X + d[0], cp.Y + d[1], cp.Z + d[2]}
+}
+
+// ChunkConnectivity records which pairs of chunk faces are connected through voxels that can be
+// seen through. Bit a*6+b is set if face a is connected to face b.
+type ChunkConnectivity uint64
+
+// FullConnectivity connects every face to every other face, as in a chunk of air
+const FullConnectivity ChunkConnectivity = 1<<36 - 1
+
+// Connected returns true if an empty path through the chunk connects the two faces
+func (c ChunkConnectivity) Connected(a, b ChunkFace) bool {
+	return c&(1<<uint(int(a)*6+int(b))) != 0
+}
+
+// connect marks every pair of faces in the given face set as connected
+func (c ChunkConnectivity) connect(faces [6]bool) ChunkConnectivity {
+	for a, touchesA := range faces {
+		for b, touchesB := range faces {
+			if touchesA && touchesB {
+				c |= 1 << uint(a*6+b)
+			}
+		}
+	}
+	return c
+}
+
+// ComputeConnectivity flood fills every region of non-opaque voxels in the chunk, and connects
+// the chunk faces touched by each region
+func ComputeConnectivity(chunk *Chunk) ChunkConnectivity {
+	sx, sy, sz := chunk.Sx, chunk.Sy, chunk.Sz
+	index := func(x, y, z int) int { return (z*sy+y)*sx + x }
+	visited := make([]bool, sx*sy*sz)
+	connectivity := ChunkConnectivity(0)
+
+	stack := make([][3]int, 0, 64)
+	for z := 0; z < sz; z++ {
+		for y := 0; y < sy; y++ {
+			for x := 0; x < sx; x++ {
+				if visited[index(x, y, z)] || chunk.At(x, y, z).Type().Opaque {
+					continue
+				}
+
+				faces := [6]bool{}
+				visited[index(x, y, z)] = true
+				stack = append(stack[:0], [3]int{x, y, z})
+				for len(stack) > 0 {
+					p := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+
+					faces[FaceWest] = faces[FaceWest] || p[0] == 0
+					faces[FaceEast] = faces[FaceEast] || p[0] == sx-1
+					faces[FaceDown] = faces[FaceDown] || p[1] == 0
+					faces[FaceUp] = faces[FaceUp] || p[1] == sy-1
+					faces[FaceNorth] = faces[FaceNorth] || p[2] == 0
+					faces[FaceSouth] = faces[FaceSouth] || p[2] == sz-1
+
+					for _, d := range lightDirections {
+						nx, ny, nz := p[0]+d[0], p[1]+d[1], p[2]+d[2]
+						if nx < 0 || ny < 0 || nz < 0 || nx >= sx || ny >= sy || nz >= sz {
+							continue
+						}
+						i := index(nx, ny, nz)
+						if visited[i] || chunk.At(nx, ny, nz).Type().Opaque {
+							continue
+						}
+						visited[i] = true
+						stack = append(stack, [3]int{nx, ny, nz})
+					}
+				}
+				connectivity = connectivity.connect(faces)
+			}
+		}
+	}
+	return connectivity
+}
+
+// visibilityStep is a chunk queued by the visibility search
+type visibilityStep struct {
+	Position ChunkPos
+
+	// Entered is the face the search entered the chunk through
+	Entered ChunkFace
+
+	// Directions holds the faces the search has left chunks through on its way here
+	Directions [6]bool
+}
+
+// VisibleChunks returns the chunks that may be visible from the chunk containing the camera.
+// Connectivity returns the face connectivity of the chunk at a position, or false if there is no
+// chunk, in which case the search does not continue past it.
+//
+// The chunks are searched breadth first, in the style of the cave culling algorithm by Tommaso
+// Checchi. A chunk is left through a face only if it is connected to the face the chunk was entered
+// through, and the search never travels in a direction opposite to one it has already traveled in,
+// since a line of sight can not turn back on itself.
+func VisibleChunks(camera ChunkPos, connectivity func(ChunkPos) (ChunkConnectivity, bool)) map[ChunkPos]bool {
+	visible := map[ChunkPos]bool{camera: true}
+
+	// the camera chunk is left through every face, since the camera could be anywhere inside it
+	queue := make([]visibilityStep, 0, 64)
+	for _, face := range chunkFaces {
+		next := face.Neighbor(camera)
+		if _, exists := connectivity(next); !exists {
+			continue
+		}
+		step := visibilityStep{Position: next, Entered: face.Opposite()}
+		step.Directions[face] = true
+		visible[next] = true
+		queue = append(queue, step)
+	}
+
+	for len(queue) > 0 {
+		step := queue[0]
+		queue = queue[1:]
+		connections, _ := connectivity(step.Position)
+
+		for _, face := range chunkFaces {
+			if step.Directions[face.Opposite()] {
+				continue
+			}
+			if !connections.Connected(step.Entered, face) {
+				continue
+			}
+			next := face.Neighbor(step.Position)
+			if visible[next] {
+				continue
+			}
+			if _, exists := connectivity(next); !exists {
+				continue
+			}
+			visible[next] = true
+			nextStep := visibilityStep{Position: next, Entered: face.Opposite(), Directions: step.Directions}
+			nextStep.Directions[face] = true
+			queue = append(queue, nextStep)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,195 @@
+package game
+
+import (
+	"testing"
+)
+
+// rockChunk creates a chunk of solid rock, with the given boxes carved out of it.
+// Each box is given as its lower and upper corner, inclusive.
+func rockChunk(size int, cp ChunkPos, carved ...[2][3]int) *Chunk {
+	chunk := NewChunk(size, 0, cp.X, cp.Y, cp.Z)
+	chunk.Data.Fill(Voxel{Block: ColorBlock, R: 120, G: 120, B: 120})
+	for _, box := range carved {
+		for x := box[0][0]; x <= box[1][0]; x++ {
+			for y := box[0][1]; y <= box[1][1]; y++ {
+				for z := box[0][2]; z <= box[1][2]; z++ {
+					chunk.Set(x, y, z, EmptyVoxel)
+				}
+			}
+		}
+	}
+	return chunk
+}
+
+func TestChunkConnectivity(t *testing.T) {
+	// tunnels run through the middle of the chunk, so they only touch the faces they end at
+	tunnelX := [2][3]int{{0, 3, 3}, {7, 4, 4}}
+	tunnelY := [2][3]int{{1, 0, 1}, {1, 7, 1}}
+	bend := [2][3]int{{3, 3, 3}, {4, 7, 4}}
+	shortX := [2][3]int{{0, 3, 3}, {4, 4, 4}}
+
+	glassWall := rockChunk(8, ChunkPos{}, tunnelX)
+	for y := 3; y <= 4; y++ {
+		for z := 3; z <= 4; z++ {
+			glassWall.Set(4, y, z, Voxel{Block: GlassBlock})
+		}
+	}
+	stoneWall := rockChunk(8, ChunkPos{}, tunnelX)
+	stoneWall.Set(4, 3, 3, Voxel{Block: ColorBlock})
+
+	cases := []struct {
+		name      string
+		chunk     *Chunk
+		connected [][2]ChunkFace
+	}{
+		{"solid", rockChunk(8, ChunkPos{}), nil},
+		{"tunnel", rockChunk(8, ChunkPos{}, tunnelX), [][2]ChunkFace{{FaceWest, FaceEast}}},
+		{"bend", rockChunk(8, ChunkPos{}, shortX, bend), [][2]ChunkFace{{FaceWest, FaceUp}}},
+		{"separate tunnels", rockChunk(8, ChunkPos{}, tunnelX, tunnelY), [][2]ChunkFace{{FaceWest, FaceEast}, {FaceDown, FaceUp}}},
+		{"crossing tunnels", rockChunk(8, ChunkPos{}, tunnelX, bend), [][2]ChunkFace{{FaceWest, FaceEast}, {FaceWest, FaceUp}, {FaceEast, FaceUp}}},
+		{"glass wall", glassWall, [][2]ChunkFace{{FaceWest, FaceEast}}},
+		{"partial stone wall", stoneWall, [][2]ChunkFace{{FaceWest, FaceEast}}},
+	}
+	for _, c := range cases {
+		expected := map[[2]ChunkFace]bool{}
+		for _, pair := range c.connected {
+			expected[pair] = true
+			expected[[2]ChunkFace{pair[1], pair[0]}] = true
+		}
+		connectivity := ComputeConnectivity(c.chunk)
+		for _, a := range chunkFaces {
+			for _, b := range chunkFaces {
+				if a == b {
+					continue
+				}
+				if connected := connectivity.Connected(a, b); connected != expected[[2]ChunkFace{a, b}] {
+					t.Errorf("%s: expected faces %d and %d connected=%t, was %t", c.name, a, b, !connected, connected)
+				}
+			}
+		}
+	}
+
+	if connectivity := ComputeConnectivity(NewChunk(8, 0, 0, 0, 0)); connectivity != FullConnectivity {
+		t.Errorf("expected every face of an empty chunk to be connected, was %b", connectivity)
+	}
+
+	// a stone voxel closing the tunnel separates its ends
+	closed := rockChunk(8, ChunkPos{}, tunnelX)
+	for y := 3; y <= 4; y++ {
+		for z := 3; z <= 4; z++ {
+			closed.Set(4, y, z, Voxel{Block: ColorBlock})
+		}
+	}
+	if ComputeConnectivity(closed).Connected(FaceWest, FaceEast) {
+		t.Error("expected a closed tunnel to separate the west and east faces")
+	}
+}
+
+// connectivityMap looks up chunk connectivity from a map, as a chunk graph for VisibleChunks
+type connectivityMap map[ChunkPos]ChunkConnectivity
+
+func (m connectivityMap) lookup(cp ChunkPos) (ChunkConnectivity, bool) {
+	c, exists := m[cp]
+	return c, exists
+}
+
+// connected returns the connectivity of a chunk where each given pair of faces is connected
+func connected(pairs ...[2]ChunkFace) ChunkConnectivity {
+	c := ChunkConnectivity(0)
+	for _, pair := range pairs {
+		faces := [6]bool{}
+		faces[pair[0]], faces[pair[1]] = true, true
+		c = c.connect(faces)
+	}
+	return c
+}
+
+func expectVisible(t *testing.T, name string, visible map[ChunkPos]bool, expected []ChunkPos) {
+	t.Helper()
+	for _, cp := range expected {
+		if !visible[cp] {
+			t.Errorf("%s: expected chunk %v to be visible", name, cp)
+		}
+	}
+	if len(visible) != len(expected) {
+		t.Errorf("%s: expected %d visible chunks, got %d: %v", name, len(expected), len(visible), visible)
+	}
+}
+
+func TestVisibleChunksTunnel(t *testing.T) {
+	// a tunnel carved along the x axis through a 5x3x3 block of rock chunks
+	graph := connectivityMap{}
+	for x := 0; x < 5; x++ {
+		for y := -1; y <= 1; y++ {
+			for z := -1; z <= 1; z++ {
+				cp := ChunkPos{x, y, z}
+				if y == 0 && z == 0 {
+					graph[cp] = ComputeConnectivity(rockChunk(8, cp, [2][3]int{{0, 3, 3}, {7, 4, 4}}))
+				} else {
+					graph[cp] = ComputeConnectivity(rockChunk(8, cp))
+				}
+			}
+		}
+	}
+
+	// the rock surrounding the tunnel is hidden, except for the walls of the camera chunk
+	visible := VisibleChunks(ChunkPos{0, 0, 0}, graph.lookup)
+	expectVisible(t, "tunnel", visible, []ChunkPos{
+		{0, 0, 0}, {1, 0, 0}, {2, 0, 0}, {3, 0, 0}, {4, 0, 0},
+		{0, -1, 0}, {0, 1, 0}, {0, 0, -1}, {0, 0, 1},
+	})
+}
+
+func TestVisibleChunksTurnBack(t *testing.T) {
+	// a cave leading east, south and then back west. the search may not turn back west after
+	// heading east, so the end of the cave is hidden from the camera.
+	graph := connectivityMap{}
+	for x := 0; x <= 2; x++ {
+		for z := 0; z <= 2; z++ {
+			graph[ChunkPos{x, 0, z}] = 0
+		}
+	}
+	graph[ChunkPos{1, 0, 0}] = connected([2]ChunkFace{FaceWest, FaceEast})
+	graph[ChunkPos{2, 0, 0}] = connected([2]ChunkFace{FaceWest, FaceSouth})
+	graph[ChunkPos{2, 0, 1}] = connected([2]ChunkFace{FaceNorth, FaceSouth})
+	graph[ChunkPos{2, 0, 2}] = connected([2]ChunkFace{FaceNorth, FaceWest})
+	graph[ChunkPos{1, 0, 2}] = connected([2]ChunkFace{FaceEast, FaceWest})
+	graph[ChunkPos{0, 0, 2}] = FullConnectivity
+
+	visible := VisibleChunks(ChunkPos{0, 0, 0}, graph.lookup)
+	expectVisible(t, "turn back", visible, []ChunkPos{
+		{0, 0, 0}, {1, 0, 0}, {0, 0, 1},
+		{2, 0, 0}, {2, 0, 1}, {2, 0, 2},
+	})
+}
+
+func TestVisibleChunksOpen(t *testing.T) {
+	// every chunk is visible in open air, but the search stops at missing chunks
+	graph := connectivityMap{}
+	expected := []ChunkPos{}
+	for x := -2; x <= 2; x++ {
+		for y := -2; y <= 2; y++ {
+			for z := -2; z <= 2; z++ {
+				cp := ChunkPos{x, y, z}
+				graph[cp] = FullConnectivity
+				expected = append(expected, cp)
+			}
+		}
+	}
+	visible := VisibleChunks(ChunkPos{0, 0, 0}, graph.lookup)
+	expectVisible(t, "open", visible, expected)
+
+	// a wall of missing chunks hides everything behind it
+	for y := -2; y <= 2; y++ {
+		for z := -2; z <= 2; z++ {
+			delete(graph, ChunkPos{1, y, z})
+		}
+	}
+	visible = VisibleChunks(ChunkPos{0, 0, 0}, graph.lookup)
+	if len(visible) != 3*5*5 {
+		t.Errorf("expected %d visible chunks, got %d", 3*5*5, len(visible))
+	}
+	if visible[ChunkPos{2, 0, 0}] {
+		t.Error("expected chunks behind missing chunks to be hidden")
+	}
+}
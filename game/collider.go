@@ -0,0 +1,175 @@
+package game
+
+import (
+	"github.com/johanhenriksson/goworld/math"
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// colliderSkin is the gap kept between a collider and the voxels it rests against,
+// so that touching faces are not counted as overlapping
+const colliderSkin = 1e-3
+
+// groundProbe is how far below a collider solid ground is detected
+const groundProbe = 0.01
+
+// BoxCollider is an axis-aligned box swept through solid voxels, one axis at a time.
+// The position of the collider is the center of the bottom face of the box.
+type BoxCollider struct {
+	Width  float32
+	Height float32
+
+	// StepHeight is the highest ledge a grounded collider climbs onto instead of stopping
+	StepHeight float32
+
+	// Solid returns true if the voxel at the given world position blocks movement
+	Solid func(x, y, z int) bool
+}
+
+// Collision describes the contacts of a collider after moving
+type Collision struct {
+	// Grounded is true if the collider is resting on solid ground
+	Grounded bool
+
+	// Ceiling is true if upward movement was blocked
+	Ceiling bool
+
+	// WallX and WallZ are true if horizontal movement along the axis was blocked
+	WallX bool
+	WallZ bool
+
+	// Stepped is true if the collider climbed onto a ledge
+	Stepped bool
+}
+
+// NewPlayerCollider creates a collider the size of the player
+func NewPlayerCollider(solid func(x, y, z int) bool) *BoxCollider {
+	return &BoxCollider{
+		Width:      0.6,
+		Height:     1.8,
+		StepHeight: 1,
+		Solid:      solid,
+	}
+}
+
+// Move sweeps the collider from position by delta, vertically first and then along each horizontal
+// axis. Every voxel passed on the way is checked, so the collider can't tunnel through thin walls
+// at high speeds. Grounded colliders climb ledges up to StepHeight.
+func (c *BoxCollider) Move(position, delta vec3.T, grounded bool) (vec3.T, Collision) {
+	result := Collision{}
+
+	dy := c.sweep(position, 1, delta.Y)
+	position.Y += dy
+	if dy != delta.Y {
+		if delta.Y > 0 {
+			result.Ceiling = true
+		} else {
+			grounded = true
+		}
+	}
+
+	for _, axis := range []int{0, 2} {
+		d := delta.Slice()[axis]
+		moved := c.sweep(position, axis, d)
+		if moved != d && grounded && c.StepHeight > 0 {
+			if raised, ok := c.step(position, axis, d, moved); ok {
+				position = raised
+				result.Stepped = true
+				continue
+			}
+		}
+		if moved != d {
+			if axis == 0 {
+				result.WallX = true
+			} else {
+				result.WallZ = true
+			}
+		}
+		position = setAxis(position, axis, position.Slice()[axis]+moved)
+	}
+
+	result.Grounded = c.sweep(position, 1, -groundProbe) != -groundProbe
+	return position, result
+}
+
+// step attempts to climb onto a ledge blocking horizontal movement along an axis, by lifting the
+// collider, moving it along the axis and lowering it back down. Returns the new position if the
+// collider got further than the blocked move.
+func (c *BoxCollider) step(position vec3.T, axis int, d, blocked float32) (vec3.T, bool) {
+	up := c.sweep(position, 1, c.StepHeight)
+	raised := position
+	raised.Y += up
+	moved := c.sweep(raised, axis, d)
+	if math.Abs(moved) <= math.Abs(blocked) {
+		return position, false
+	}
+	raised = setAxis(raised, axis, raised.Slice()[axis]+moved)
+	raised.Y += c.sweep(raised, 1, -up)
+	return raised, true
+}
+
+// bounds returns the corners of the collider box at the given position
+func (c *BoxCollider) bounds(position vec3.T) ([3]float32, [3]float32) {
+	w := c.Width / 2
+	min := [3]float32{position.X - w, position.Y, position.Z - w}
+	max := [3]float32{position.X + w, position.Y + c.Height, position.Z + w}
+	return min, max
+}
+
+// sweep returns how far the collider can move along an axis, up to d, before hitting a solid voxel
+func (c *BoxCollider) sweep(position vec3.T, axis int, d float32) float32 {
+	if d == 0 {
+		return 0
+	}
+	min, max := c.bounds(position)
+
+	// range of voxels overlapped along the other two axes
+	var lo, hi [3]int
+	for i := 0; i < 3; i++ {
+		lo[i] = int(math.Floor(min[i] + colliderSkin))
+		hi[i] = int(math.Floor(max[i] - colliderSkin))
+	}
+
+	if d > 0 {
+		for k := hi[axis] + 1; float32(k) < max[axis]+d; k++ {
+			if c.blocked(axis, k, lo, hi) {
+				return float32(k) - max[axis] - colliderSkin/2
+			}
+		}
+	} else {
+		for k := lo[axis] - 1; float32(k+1) > min[axis]+d; k-- {
+			if c.blocked(axis, k, lo, hi) {
+				return float32(k+1) - min[axis] + colliderSkin/2
+			}
+		}
+	}
+	return d
+}
+
+// blocked returns true if any voxel in the layer k along the axis, within the given range
+// on the other axes, is solid
+func (c *BoxCollider) blocked(axis, k int, lo, hi [3]int) bool {
+	lo[axis], hi[axis] = k, k
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				if c.Solid(x, y, z) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// setAxis returns v with the component along the given axis replaced
+func setAxis(v vec3.T, axis int, value float32) vec3.T {
+	switch axis {
+	case 0:
+		v.X = value
+	case 1:
+		v.Y = value
+	default:
+		v.Z = value
+	}
+	return v
+}
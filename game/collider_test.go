@@ -0,0 +1,119 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// testTerrain is a set of solid voxels on top of a flat floor, with its top face at y = 1
+type testTerrain map[WorldPos]bool
+
+func (t testTerrain) Solid(x, y, z int) bool {
+	return y <= 0 || t[WorldPos{x, y, z}]
+}
+
+func expectPosition(t *testing.T, name string, actual, expected vec3.T) {
+	t.Helper()
+	if d := actual.Sub(expected).Length(); d > 0.01 {
+		t.Errorf("%s: expected position %v, got %v", name, expected, actual)
+	}
+}
+
+func TestColliderLanding(t *testing.T) {
+	collider := NewPlayerCollider(testTerrain{}.Solid)
+	position, collision := collider.Move(vec3.New(0.5, 5, 0.5), vec3.New(0, -20, 0), false)
+	expectPosition(t, "landing", position, vec3.New(0.5, 1, 0.5))
+	if !collision.Grounded {
+		t.Error("expected the collider to be grounded after landing")
+	}
+
+	// resting on the ground
+	position, collision = collider.Move(position, vec3.New(0.1, 0, 0), true)
+	if !collision.Grounded || collision.WallX {
+		t.Errorf("expected to slide along the ground, got %+v", collision)
+	}
+
+	// walking off a ledge
+	terrain := testTerrain{}
+	for x := -2; x <= 0; x++ {
+		terrain[WorldPos{x, 1, 0}] = true
+	}
+	collider = NewPlayerCollider(terrain.Solid)
+	position, collision = collider.Move(vec3.New(0.5, 2, 0.5), vec3.New(1, 0, 0), true)
+	if collision.Grounded {
+		t.Errorf("expected the collider to leave the ground at %v", position)
+	}
+}
+
+func TestColliderWalls(t *testing.T) {
+	terrain := testTerrain{}
+	for y := 1; y <= 3; y++ {
+		for z := -2; z <= 2; z++ {
+			terrain[WorldPos{3, y, z}] = true
+		}
+	}
+	collider := NewPlayerCollider(terrain.Solid)
+
+	// a fast move must not tunnel through the wall
+	position, collision := collider.Move(vec3.New(0.5, 1, 0.5), vec3.New(50, 0, 0), true)
+	expectPosition(t, "wall", position, vec3.New(2.7, 1, 0.5))
+	if !collision.WallX || collision.Stepped {
+		t.Errorf("expected to be stopped by the wall, got %+v", collision)
+	}
+
+	// sliding along the wall
+	position, collision = collider.Move(position, vec3.New(1, 0, 1), true)
+	expectPosition(t, "slide", position, vec3.New(2.7, 1, 1.5))
+	if !collision.WallX || collision.WallZ {
+		t.Errorf("expected to slide along the wall, got %+v", collision)
+	}
+}
+
+func TestColliderStepUp(t *testing.T) {
+	terrain := testTerrain{WorldPos{2, 1, 0}: true}
+	collider := NewPlayerCollider(terrain.Solid)
+
+	position, collision := collider.Move(vec3.New(0.5, 1, 0.5), vec3.New(1.5, 0, 0), true)
+	expectPosition(t, "step", position, vec3.New(2, 2, 0.5))
+	if !collision.Stepped || !collision.Grounded {
+		t.Errorf("expected to step onto the block, got %+v", collision)
+	}
+
+	// no steps in mid air
+	position, collision = collider.Move(vec3.New(0.5, 1.5, 0.5), vec3.New(1.5, 0, 0), false)
+	if collision.Stepped || !collision.WallX {
+		t.Errorf("expected airborne colliders not to climb, got %+v at %v", collision, position)
+	}
+
+	// walls two blocks high are too tall to climb
+	terrain[WorldPos{2, 2, 0}] = true
+	position, collision = collider.Move(vec3.New(0.5, 1, 0.5), vec3.New(1.5, 0, 0), true)
+	expectPosition(t, "tall wall", position, vec3.New(1.7, 1, 0.5))
+	if collision.Stepped || !collision.WallX {
+		t.Errorf("expected to be stopped by the wall, got %+v", collision)
+	}
+
+	// a low ceiling above the step leaves no room to climb
+	terrain = testTerrain{WorldPos{2, 1, 0}: true, WorldPos{2, 3, 0}: true, WorldPos{1, 3, 0}: true}
+	collider = NewPlayerCollider(terrain.Solid)
+	position, collision = collider.Move(vec3.New(1.5, 1, 0.5), vec3.New(1, 0, 0), true)
+	if collision.Stepped || position.Y != 1 {
+		t.Errorf("expected no room to step under the overhang, got %+v at %v", collision, position)
+	}
+}
+
+func TestColliderCeiling(t *testing.T) {
+	terrain := testTerrain{}
+	for x := -1; x <= 1; x++ {
+		for z := -1; z <= 1; z++ {
+			terrain[WorldPos{x, 4, z}] = true
+		}
+	}
+	collider := NewPlayerCollider(terrain.Solid)
+	position, collision := collider.Move(vec3.New(0.5, 1, 0.5), vec3.New(0, 3, 0), false)
+	expectPosition(t, "ceiling", position, vec3.New(0.5, 2.2, 0.5))
+	if !collision.Ceiling || collision.Grounded {
+		t.Errorf("expected to hit the ceiling, got %+v", collision)
+	}
+}
@@ -0,0 +1,296 @@
+package game
+
+// voxelQuad is a single rectangular face of voxel geometry, before triangulation.
+// X, Y, Z is the position of the transparent voxel the face was generated from.
+// The corners are ordered to produce front facing triangles for the quads normal.
+type voxelQuad struct {
+	N       byte
+	X, Y, Z int
+	V       [4]VoxelVertex
+}
+
+// face returns the position of the voxel the quad is a face of,
+// which is the neighbor of the transparent voxel opposite to the quad normal.
+func (q *voxelQuad) face() (int, int, int) {
+	switch q.N {
+	case 1:
+		return q.X - 1, q.Y, q.Z
+	case 2:
+		return q.X + 1, q.Y, q.Z
+	case 3:
+		return q.X, q.Y - 1, q.Z
+	case 4:
+		return q.X, q.Y + 1, q.Z
+	case 5:
+		return q.X, q.Y, q.Z - 1
+	default:
+		return q.X, q.Y, q.Z + 1
+	}
+}
+
+// quadLayer identifies the plane a quad lies in
+type quadLayer struct {
+	N     byte
+	Plane int
+}
+
+// quadVertices triangulates a list of quads
+func quadVertices(quads []voxelQuad) []VoxelVertex {
+	data := make([]VoxelVertex, 0, 6*len(quads))
+	for _, q := range quads {
+		v1, v2, v3, v4 := q.V[0], q.V[1], q.V[2], q.V[3]
+		if q.N == 4 || q.N == 5 {
+			data = append(data, v1, v3, v2, v2, v3, v4)
+		} else {
+			data = append(data, v2, v3, v1, v4, v3, v2)
+		}
+	}
+	return data
+}
+
+// quadAxes returns the axis perpendicular to quads with the given normal id,
+// followed by the two axes spanning the quad plane.
+func quadAxes(n byte) (int, int, int) {
+	switch n {
+	case 1, 2:
+		return 0, 2, 1
+	case 3, 4:
+		return 1, 0, 2
+	default:
+		return 2, 0, 1
+	}
+}
+
+// axis returns the component of a quad position along the given axis
+func (q *voxelQuad) axis(i int) int {
+	switch i {
+	case 0:
+		return q.X
+	case 1:
+		return q.Y
+	default:
+		return q.Z
+	}
+}
+
+// vertexAxis returns the component of a vertex position along the given axis
+func vertexAxis(v *VoxelVertex, i int) byte {
+	switch i {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// setVertexAxis sets the component of a vertex position along the given axis
+func setVertexAxis(v *VoxelVertex, i int, value byte) {
+	switch i {
+	case 0:
+		v.X = value
+	case 1:
+		v.Y = value
+	default:
+		v.Z = value
+	}
+}
+
+// uniform returns true if all corners of the quad have the same occlusion and block light,
+// which means it can be stretched without changing its appearance.
+func (q *voxelQuad) uniform() bool {
+	a := q.V[0]
+	for _, b := range q.V[1:] {
+		if b.O != a.O || b.LR != a.LR || b.LG != a.LG || b.LB != a.LB {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeable returns true if two uniform quads in the same plane look identical
+func (q *voxelQuad) mergeable(o *voxelQuad) bool {
+	a, b := q.V[0], o.V[0]
+	return a.R == b.R && a.G == b.G && a.B == b.B && a.A == b.A && a.O == b.O &&
+		a.LR == b.LR && a.LG == b.LG && a.LB == b.LB
+}
+
+// bakeBlockLight sets the block light of each quad corner to the average light of the
+// transparent voxels touching the corner, in the layer of voxels in front of the quad.
+func bakeBlockLight(view *Neighborhood, quads []voxelQuad) {
+	for i := range quads {
+		q := &quads[i]
+		_, ua, va := quadAxes(q.N)
+		for j := range q.V {
+			v := &q.V[j]
+			cu, cv := int(vertexAxis(v, ua)), int(vertexAxis(v, va))
+
+			sum, count := [3]int{}, 0
+			for du := -1; du <= 0; du++ {
+				for dv := -1; dv <= 0; dv++ {
+					p := [3]int{q.X, q.Y, q.Z}
+					p[ua] = cu + du
+					p[va] = cv + dv
+					if view.At(p[0], p[1], p[2]).Type().Opaque {
+						continue
+					}
+					light := view.BlockLight(p[0], p[1], p[2])
+					for c := range sum {
+						sum[c] += int(light[c])
+					}
+					count++
+				}
+			}
+
+			if count == 0 {
+				// every voxel touching the corner is opaque, which happens if the voxel in front
+				// of the quad was filled after the quad was computed. use its light as is.
+				light := view.BlockLight(q.X, q.Y, q.Z)
+				for c := range sum {
+					sum[c] = int(light[c])
+				}
+				count = 1
+			}
+			level := func(c int) byte {
+				return byte(255 * sum[c] / (count * MaxLightLevel))
+			}
+			v.LR, v.LG, v.LB = level(0), level(1), level(2)
+		}
+	}
+}
+
+// splitTranslucent separates faces of translucent voxels from the opaque faces,
+// and sets the alpha of every quad corner from the block type of its voxel.
+func splitTranslucent(view *Neighborhood, quads []voxelQuad) ([]voxelQuad, []voxelQuad) {
+	opaque := quads[:0]
+	translucent := make([]voxelQuad, 0, 16)
+	for _, q := range quads {
+		block := view.At(q.face()).Type()
+		alpha := byte(255)
+		if block.Translucent() {
+			alpha = block.Alpha
+		}
+		for i := range q.V {
+			q.V[i].A = alpha
+		}
+		if alpha < 255 {
+			translucent = append(translucent, q)
+		} else {
+			opaque = append(opaque, q)
+		}
+	}
+	return opaque, translucent
+}
+
+// stretch returns a copy of the quad covering width x height faces
+func (q voxelQuad) stretch(width, height int) voxelQuad {
+	_, ua, va := quadAxes(q.N)
+	for i := range q.V {
+		v := &q.V[i]
+		du := int(vertexAxis(v, ua)) - q.axis(ua)
+		dv := int(vertexAxis(v, va)) - q.axis(va)
+		setVertexAxis(v, ua, byte(q.axis(ua)+du*width))
+		setVertexAxis(v, va, byte(q.axis(va)+dv*height))
+	}
+	return q
+}
+
+// mergeQuads greedily merges adjacent coplanar quads with the same normal, color and
+// uniform occlusion into larger rectangles. Quads with varying occlusion are kept as is.
+func mergeQuads(quads []voxelQuad) []voxelQuad {
+	// group quads by plane, in order of first appearance
+	layers := make(map[quadLayer][]int)
+	order := make([]quadLayer, 0, 16)
+	for i := range quads {
+		q := &quads[i]
+		pa, _, _ := quadAxes(q.N)
+		key := quadLayer{N: q.N, Plane: q.axis(pa)}
+		if _, exists := layers[key]; !exists {
+			order = append(order, key)
+		}
+		layers[key] = append(layers[key], i)
+	}
+
+	merged := make([]voxelQuad, 0, len(quads)/2)
+	used := make([]bool, len(quads))
+	for _, key := range order {
+		indices := layers[key]
+		_, ua, va := quadAxes(key.N)
+
+		// find the bounds of the layer
+		minU, minV := quads[indices[0]].axis(ua), quads[indices[0]].axis(va)
+		maxU, maxV := minU, minV
+		for _, i := range indices {
+			u, v := quads[i].axis(ua), quads[i].axis(va)
+			if u < minU {
+				minU = u
+			}
+			if u > maxU {
+				maxU = u
+			}
+			if v < minV {
+				minV = v
+			}
+			if v > maxV {
+				maxV = v
+			}
+		}
+
+		// build a 2D grid of quad indices
+		w, h := maxU-minU+1, maxV-minV+1
+		grid := make([]int, w*h)
+		for i := range grid {
+			grid[i] = -1
+		}
+		for _, i := range indices {
+			u, v := quads[i].axis(ua)-minU, quads[i].axis(va)-minV
+			grid[v*w+u] = i
+		}
+
+		for v := 0; v < h; v++ {
+			for u := 0; u < w; u++ {
+				i := grid[v*w+u]
+				if i < 0 || used[i] {
+					continue
+				}
+				used[i] = true
+				q := &quads[i]
+				if !q.uniform() {
+					merged = append(merged, *q)
+					continue
+				}
+
+				fits := func(j int) bool {
+					return j >= 0 && !used[j] && quads[j].uniform() && q.mergeable(&quads[j])
+				}
+
+				// extend along u
+				width := 1
+				for u+width < w && fits(grid[v*w+u+width]) {
+					used[grid[v*w+u+width]] = true
+					width++
+				}
+
+				// extend along v, one full row at a time
+				height := 1
+			rows:
+				for v+height < h {
+					row := (v + height) * w
+					for k := 0; k < width; k++ {
+						if !fits(grid[row+u+k]) {
+							break rows
+						}
+					}
+					for k := 0; k < width; k++ {
+						used[grid[row+u+k]] = true
+					}
+					height++
+				}
+
+				merged = append(merged, q.stretch(width, height))
+			}
+		}
+	}
+	return merged
+}
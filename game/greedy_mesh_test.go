@@ -0,0 +1,229 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type faceKey struct {
+	N       byte
+	Plane   int
+	U, V    int
+	Winding int
+}
+
+type faceSample struct {
+	R, G, B, A byte
+	O          [4]float32
+	L          [4][3]float32
+}
+
+// rasterizeFaces splits triangulated voxel quads into unit faces, sampling color,
+// occlusion and block light at the corners of each unit face.
+func rasterizeFaces(t *testing.T, data []VoxelVertex) map[faceKey]faceSample {
+	t.Helper()
+	if len(data)%6 != 0 {
+		t.Fatalf("vertex count %d is not a multiple of 6", len(data))
+	}
+
+	faces := make(map[faceKey]faceSample)
+	for i := 0; i < len(data); i += 6 {
+		tri := data[i : i+6]
+		n := tri[0].N
+		pa, ua, va := quadAxes(n)
+
+		// winding of the first triangle along the plane axis
+		pos := func(v VoxelVertex) [3]int { return [3]int{int(v.X), int(v.Y), int(v.Z)} }
+		a, b, c := pos(tri[0]), pos(tri[1]), pos(tri[2])
+		e1 := [3]int{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+		e2 := [3]int{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+		cross := [3]int{
+			e1[1]*e2[2] - e1[2]*e2[1],
+			e1[2]*e2[0] - e1[0]*e2[2],
+			e1[0]*e2[1] - e1[1]*e2[0],
+		}
+		winding := 1
+		if cross[pa] < 0 {
+			winding = -1
+		}
+
+		// unique corners
+		corners := map[[2]int]VoxelVertex{}
+		for _, v := range tri {
+			corners[[2]int{int(vertexAxis(&v, ua)), int(vertexAxis(&v, va))}] = v
+		}
+		if len(corners) != 4 {
+			t.Fatalf("quad %d has %d unique corners", i/6, len(corners))
+		}
+		minU, minV, maxU, maxV := 1<<30, 1<<30, -1, -1
+		for c := range corners {
+			if c[0] < minU {
+				minU = c[0]
+			}
+			if c[0] > maxU {
+				maxU = c[0]
+			}
+			if c[1] < minV {
+				minV = c[1]
+			}
+			if c[1] > maxV {
+				maxV = c[1]
+			}
+		}
+		c00, c10 := corners[[2]int{minU, minV}], corners[[2]int{maxU, minV}]
+		c01, c11 := corners[[2]int{minU, maxV}], corners[[2]int{maxU, maxV}]
+		interpolate := func(attr func(VoxelVertex) byte, u, v int) float32 {
+			fu := float32(u-minU) / float32(maxU-minU)
+			fv := float32(v-minV) / float32(maxV-minV)
+			return (1-fu)*(1-fv)*float32(attr(c00)) + fu*(1-fv)*float32(attr(c10)) +
+				(1-fu)*fv*float32(attr(c01)) + fu*fv*float32(attr(c11))
+		}
+		sample := func(u, v int) float32 {
+			return interpolate(func(v VoxelVertex) byte { return v.O }, u, v)
+		}
+		light := func(u, v int) [3]float32 {
+			return [3]float32{
+				interpolate(func(v VoxelVertex) byte { return v.LR }, u, v),
+				interpolate(func(v VoxelVertex) byte { return v.LG }, u, v),
+				interpolate(func(v VoxelVertex) byte { return v.LB }, u, v),
+			}
+		}
+
+		plane := int(vertexAxis(&tri[0], pa))
+		for u := minU; u < maxU; u++ {
+			for v := minV; v < maxV; v++ {
+				key := faceKey{N: n, Plane: plane, U: u, V: v, Winding: winding}
+				if _, exists := faces[key]; exists {
+					t.Fatalf("face %v is covered twice", key)
+				}
+				faces[key] = faceSample{
+					R: tri[0].R, G: tri[0].G, B: tri[0].B, A: tri[0].A,
+					O: [4]float32{sample(u, v), sample(u+1, v), sample(u, v+1), sample(u+1, v+1)},
+					L: [4][3]float32{light(u, v), light(u+1, v), light(u, v+1), light(u+1, v+1)},
+				}
+			}
+		}
+	}
+	return faces
+}
+
+func assertMeshesEquivalent(t *testing.T, chunk *Chunk) (int, int) {
+	t.Helper()
+	view := NewNeighborhood(chunk, nil)
+	simple := computeVertexData(view, MeshSimple, 0)
+	greedy := computeVertexData(view, MeshGreedy, 0)
+	assertVerticesEquivalent(t, simple.Opaque, greedy.Opaque)
+	assertVerticesEquivalent(t, simple.Translucent, greedy.Translucent)
+	return len(simple.Opaque), len(greedy.Opaque)
+}
+
+func assertVerticesEquivalent(t *testing.T, simple, greedy []VoxelVertex) {
+	t.Helper()
+	expected := rasterizeFaces(t, simple)
+	actual := rasterizeFaces(t, greedy)
+	if len(expected) != len(actual) {
+		t.Errorf("expected %d unit faces, greedy mesh covers %d", len(expected), len(actual))
+	}
+	for key, face := range expected {
+		other, exists := actual[key]
+		if !exists {
+			t.Fatalf("greedy mesh is missing face %v", key)
+		}
+		if other != face {
+			t.Fatalf("face %v differs: expected %v, was %v", key, face, other)
+		}
+	}
+}
+
+func flatChunk(size int) *Chunk {
+	chunk := NewChunk(size, 0, 0, 0, 0)
+	grass := Voxel{Block: ColorBlock, R: 72, G: 140, B: 54}
+	for z := 0; z < size; z++ {
+		for x := 0; x < size; x++ {
+			for y := 0; y < size/2; y++ {
+				chunk.Set(x, y, z, grass)
+			}
+		}
+	}
+	chunk.Light.Calculate()
+	return chunk
+}
+
+func randomChunk(size int, seed int64) *Chunk {
+	rnd := rand.New(rand.NewSource(seed))
+	colors := Voxels{
+		{Block: ColorBlock, R: 200},
+		{Block: ColorBlock, G: 200},
+		{Block: GlassBlock, B: 200},
+		{Block: WaterBlock, B: 255},
+		{Block: LampBlock, R: 255, G: 180, B: 60},
+	}
+	chunk := NewChunk(size, 0, 0, 0, 0)
+	for z := 0; z < size; z++ {
+		for x := 0; x < size; x++ {
+			for y := 0; y < size; y++ {
+				if rnd.Intn(3) == 0 {
+					chunk.Set(x, y, z, colors[rnd.Intn(len(colors))])
+				}
+			}
+		}
+	}
+	chunk.Light.Calculate()
+	return chunk
+}
+
+func TestGreedyMeshEquivalence(t *testing.T) {
+	simple, greedy := assertMeshesEquivalent(t, flatChunk(16))
+	if greedy >= simple {
+		t.Errorf("expected greedy meshing to reduce flat terrain, %d >= %d vertices", greedy, simple)
+	}
+
+	assertMeshesEquivalent(t, randomChunk(8, 1))
+	assertMeshesEquivalent(t, testWorldgen(t, 31481234, 16).Chunk(0, 0, 0))
+}
+
+func benchmarkMesher(b *testing.B, mode MeshMode, chunk *Chunk) {
+	view := NewNeighborhood(chunk, nil)
+	vertices := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := computeVertexData(view, mode, 0)
+		vertices = len(data.Opaque) + len(data.Translucent)
+	}
+	b.ReportMetric(float64(vertices), "vertices")
+}
+
+func BenchmarkMeshSimpleFlat(b *testing.B) { benchmarkMesher(b, MeshSimple, flatChunk(16)) }
+func BenchmarkMeshGreedyFlat(b *testing.B) { benchmarkMesher(b, MeshGreedy, flatChunk(16)) }
+
+func BenchmarkMeshSimpleTerrain(b *testing.B) {
+	benchmarkMesher(b, MeshSimple, testWorldgen(b, 31481234, 16).Chunk(0, 0, 0))
+}
+
+func BenchmarkMeshGreedyTerrain(b *testing.B) {
+	benchmarkMesher(b, MeshGreedy, testWorldgen(b, 31481234, 16).Chunk(0, 0, 0))
+}
+
+func TestBakeBlockLightOpaqueFront(t *testing.T) {
+	chunk := flatChunk(8)
+	view := NewNeighborhood(chunk, nil)
+	quads := computeQuads(view)
+
+	// fill the voxels in front of every quad after the quads are computed
+	for _, q := range quads {
+		if q.X >= 0 && q.Y >= 0 && q.Z >= 0 && q.X < 8 && q.Y < 8 && q.Z < 8 {
+			chunk.Set(q.X, q.Y, q.Z, Voxel{Block: ColorBlock, R: 200})
+		}
+	}
+	chunk.Light.Get(1, 4, 1).Color = [3]byte{MaxLightLevel, 0, 0}
+
+	bakeBlockLight(view, quads)
+	for _, q := range quads {
+		light := view.BlockLight(q.X, q.Y, q.Z)
+		for _, v := range q.V {
+			if int(v.LR) != 255*int(light[0])/MaxLightLevel || v.LG != 0 || v.LB != 0 {
+				t.Fatalf("expected quad corner to take the light of the voxel in front, got %d,%d,%d", v.LR, v.LG, v.LB)
+			}
+		}
+	}
+}
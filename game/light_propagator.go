@@ -0,0 +1,213 @@
+package game
+
+// lightGrid looks up light voxels by position, returning nil outside the grid
+type lightGrid func(x, y, z int) *LightVoxel
+
+// lightNode is a queued light propagation step. When removing light,
+// V and Color hold the light the voxel had before it was darkened.
+type lightNode struct {
+	X, Y, Z int
+	V       float32
+	Color   [3]byte
+}
+
+// lightDirections are the offsets to the six face neighbors of a voxel
+var lightDirections = [6][3]int{
+	{-1, 0, 0}, {1, 0, 0},
+	{0, -1, 0}, {0, 1, 0},
+	{0, 0, -1}, {0, 0, 1},
+}
+
+// lightPropagator spreads light through a grid using queue based flood fills.
+//
+// Sky light travels downwards without loss, and is multiplied by the falloff in every other
+// direction. Open voxels without anything above them receive full sky light.
+// Block light spreads from emitting voxels, losing one level per voxel in each color channel.
+// Emitting voxels may be blocked, in which case they light their surroundings but do not
+// let any light through.
+//
+// Removed light is flood filled first, after which any remaining light is spread back
+// into the darkened region, so only the voxels affected by a change are visited.
+type lightPropagator struct {
+	grid    lightGrid
+	falloff float32
+	adds    []lightNode
+	removes []lightNode
+
+	// changed is called for every voxel whose light value is modified, if set
+	changed func(x, y, z int)
+}
+
+func newLightPropagator(grid lightGrid, falloff float32) *lightPropagator {
+	return &lightPropagator{
+		grid:    grid,
+		falloff: falloff,
+	}
+}
+
+// attenuation returns the sky light multiplier when moving along the y axis by dy
+func (p *lightPropagator) attenuation(dy int) float32 {
+	if dy < 0 {
+		return 1
+	}
+	return p.falloff
+}
+
+// sky returns true if the voxel has nothing above it, and thus receives sky light
+func (p *lightPropagator) sky(x, y, z int) bool {
+	return p.grid(x, y+1, z) == nil
+}
+
+func (p *lightPropagator) notify(x, y, z int) {
+	if p.changed != nil {
+		p.changed(x, y, z)
+	}
+}
+
+// Darken removes the light of a voxel, queueing the removal of any light that originated from it
+func (p *lightPropagator) Darken(x, y, z int) {
+	lv := p.grid(x, y, z)
+	if lv == nil || (lv.V == 0 && lv.Color == [3]byte{}) {
+		return
+	}
+	p.removes = append(p.removes, lightNode{x, y, z, lv.V, lv.Color})
+	lv.V = 0
+	lv.Color = [3]byte{}
+	p.notify(x, y, z)
+}
+
+// Refresh recomputes the light of a voxel from the sky, its own emission and its neighbors,
+// queueing it for propagation if it became brighter.
+func (p *lightPropagator) Refresh(x, y, z int) {
+	lv := p.grid(x, y, z)
+	if lv == nil {
+		return
+	}
+
+	value := float32(0)
+	color := lv.Emit
+	if !lv.Blocked {
+		if p.sky(x, y, z) {
+			value = 1
+		}
+		for _, d := range lightDirections {
+			n := p.grid(x+d[0], y+d[1], z+d[2])
+			if n == nil {
+				continue
+			}
+			// light from the neighbor travels in the opposite direction
+			if v := n.V * p.attenuation(-d[1]); !n.Blocked && v > value {
+				value = v
+			}
+			for c := range color {
+				if n.Color[c] > color[c]+1 {
+					color[c] = n.Color[c] - 1
+				}
+			}
+		}
+	}
+
+	brighter := false
+	if value > lv.V {
+		lv.V = value
+		brighter = true
+	}
+	for c := range color {
+		if color[c] > lv.Color[c] {
+			lv.Color[c] = color[c]
+			brighter = true
+		}
+	}
+	if brighter {
+		p.notify(x, y, z)
+		p.adds = append(p.adds, lightNode{X: x, Y: y, Z: z})
+	}
+}
+
+// Run processes all queued removals, then spreads light until the grid is stable
+func (p *lightPropagator) Run() {
+	p.unpropagate()
+	p.propagate()
+}
+
+func (p *lightPropagator) unpropagate() {
+	for len(p.removes) > 0 {
+		r := p.removes[0]
+		p.removes = p.removes[1:]
+		for _, d := range lightDirections {
+			x, y, z := r.X+d[0], r.Y+d[1], r.Z+d[2]
+			n := p.grid(x, y, z)
+			if n == nil {
+				continue
+			}
+
+			removed := lightNode{X: x, Y: y, Z: z}
+			relight := false
+
+			if r.V > 0 && !n.Blocked && n.V > 0 {
+				if n.V <= r.V*p.attenuation(d[1]) && !p.sky(x, y, z) {
+					// the neighbor may have been lit by the removed voxel
+					removed.V = n.V
+					n.V = 0
+				} else {
+					// the neighbor has another light source, which should fill the darkened region
+					relight = true
+				}
+			}
+
+			for c := range r.Color {
+				if r.Color[c] == 0 || n.Color[c] == 0 {
+					continue
+				}
+				if n.Color[c] < r.Color[c] {
+					removed.Color[c] = n.Color[c]
+					n.Color[c] = n.Emit[c]
+				}
+				if n.Color[c] > 0 {
+					relight = true
+				}
+			}
+
+			if removed.V > 0 || removed.Color != [3]byte{} {
+				p.notify(x, y, z)
+				p.removes = append(p.removes, removed)
+			}
+			if relight {
+				p.adds = append(p.adds, lightNode{X: x, Y: y, Z: z})
+			}
+		}
+	}
+}
+
+func (p *lightPropagator) propagate() {
+	for len(p.adds) > 0 {
+		a := p.adds[0]
+		p.adds = p.adds[1:]
+		lv := p.grid(a.X, a.Y, a.Z)
+		if lv == nil {
+			continue
+		}
+		for _, d := range lightDirections {
+			x, y, z := a.X+d[0], a.Y+d[1], a.Z+d[2]
+			n := p.grid(x, y, z)
+			if n == nil || n.Blocked {
+				continue
+			}
+			brighter := false
+			if v := lv.V * p.attenuation(d[1]); !lv.Blocked && v > n.V {
+				n.V = v
+				brighter = true
+			}
+			for c := range lv.Color {
+				if lv.Color[c] > n.Color[c]+1 {
+					n.Color[c] = lv.Color[c] - 1
+					brighter = true
+				}
+			}
+			if brighter {
+				p.notify(x, y, z)
+				p.adds = append(p.adds, lightNode{X: x, Y: y, Z: z})
+			}
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package game
 
 import (
-	"fmt"
 	"github.com/johanhenriksson/goworld/math"
 )
 
+// DefaultLightFalloff is the fraction of light kept when it spreads sideways or upwards
+const DefaultLightFalloff = 0.6
+
 type LightVolume struct {
 	Falloff    float32
 	Sx, Sy, Sz int
@@ -14,6 +16,13 @@ type LightVolume struct {
 type LightVoxel struct {
 	Blocked bool
 	V       float32
+
+	// Emit holds the block light level emitted by the voxel in each color channel
+	Emit [3]byte
+
+	// Color holds the block light level of each color channel. Block light fades by one
+	// level per voxel it travels, regardless of direction.
+	Color [3]byte
 }
 
 func NewLightVolume(sx, sy, sz int) *LightVolume {
@@ -25,7 +34,7 @@ func NewLightVolume(sx, sy, sz int) *LightVolume {
 		}
 	}
 	return &LightVolume{
-		Falloff: 0.6,
+		Falloff: DefaultLightFalloff,
 		Sx:      sx,
 		Sy:      sy,
 		Sz:      sz,
@@ -33,6 +42,18 @@ func NewLightVolume(sx, sy, sz int) *LightVolume {
 	}
 }
 
+// Clone returns a copy of the light volume that shares no memory with the original
+func (lv *LightVolume) Clone() *LightVolume {
+	clone := NewLightVolume(lv.Sx, lv.Sy, lv.Sz)
+	clone.Falloff = lv.Falloff
+	for z := range lv.Data {
+		for x := range lv.Data[z] {
+			copy(clone.Data[z][x], lv.Data[z][x])
+		}
+	}
+	return clone
+}
+
 func (lv *LightVolume) Brightness(x, y, z int) float32 {
 	v := lv.Get(x, y, z)
 	if v != nil {
@@ -48,6 +69,14 @@ func (lv *LightVolume) Block(x, y, z int, blocked bool) {
 	}
 }
 
+// Emit sets the block light levels emitted by the voxel at the given position
+func (lv *LightVolume) Emit(x, y, z int, levels [3]byte) {
+	v := lv.Get(x, y, z)
+	if v != nil {
+		v.Emit = levels
+	}
+}
+
 func (lv *LightVolume) Get(x, y, z int) *LightVoxel {
 	if x < 0 || y < 0 || z < 0 || x >= lv.Sx || y >= lv.Sy || z >= lv.Sz {
 		return nil
@@ -88,26 +117,46 @@ func (lv *LightVolume) Resample(x, y, z int) float32 {
 	return nmax
 }
 
+// Calculate recomputes the entire light volume by flood filling sky light from the top layer,
+// and block light from every emitting voxel.
 func (lv *LightVolume) Calculate() {
-	i := 0
-	changed := true
-	for changed {
-		i++
-		changed = false
-		for z := 0; z < lv.Sz; z++ {
-			for x := 0; x < lv.Sx; x++ {
-				for y := 0; y < lv.Sy; y++ {
-					lp := lv.Get(x, y, z)
-					brightness := lv.Resample(x, y, z)
-					if lp.V != brightness {
-						lp.V = brightness
-						changed = true
-					}
+	p := lv.propagator()
+	for z := 0; z < lv.Sz; z++ {
+		for x := 0; x < lv.Sx; x++ {
+			for y := 0; y < lv.Sy; y++ {
+				v := &lv.Data[z][x][y]
+				v.V = 0
+				v.Color = [3]byte{}
+			}
+		}
+	}
+	for z := 0; z < lv.Sz; z++ {
+		for x := 0; x < lv.Sx; x++ {
+			for y := 0; y < lv.Sy; y++ {
+				if y == lv.Sy-1 || lv.Data[z][x][y].Emit != [3]byte{} {
+					p.Refresh(x, y, z)
 				}
 			}
 		}
 	}
-	fmt.Println("Light volume calculation finished in", i, "iterations")
+	p.Run()
+}
+
+// Update propagates the effects of changing the blocked state or emission of a single voxel.
+// Only the region whose light is affected by the change is visited.
+func (lv *LightVolume) Update(x, y, z int) {
+	if lv.Get(x, y, z) == nil {
+		return
+	}
+	p := lv.propagator()
+	p.Darken(x, y, z)
+	p.Run()
+	p.Refresh(x, y, z)
+	p.Run()
+}
+
+func (lv *LightVolume) propagator() *lightPropagator {
+	return newLightPropagator(lv.Get, lv.Falloff)
 }
 
 func (lv *LightVolume) Clear() {
@@ -0,0 +1,214 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// relaxLight is the reference lighting implementation, which resamples the
+// entire volume until nothing changes
+func relaxLight(lv *LightVolume) {
+	for z := 0; z < lv.Sz; z++ {
+		for x := 0; x < lv.Sx; x++ {
+			for y := 0; y < lv.Sy; y++ {
+				lv.Data[z][x][y].V = 0
+				lv.Data[z][x][y].Color = [3]byte{}
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for z := 0; z < lv.Sz; z++ {
+			for x := 0; x < lv.Sx; x++ {
+				for y := 0; y < lv.Sy; y++ {
+					lp := lv.Get(x, y, z)
+					if brightness := lv.Resample(x, y, z); lp.V != brightness {
+						lp.V = brightness
+						changed = true
+					}
+					if color := resampleBlockLight(lv, x, y, z); lp.Color != color {
+						lp.Color = color
+						changed = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// resampleBlockLight computes the block light of a voxel from its emission and its neighbors
+func resampleBlockLight(lv *LightVolume, x, y, z int) [3]byte {
+	lp := lv.Get(x, y, z)
+	color := lp.Emit
+	if lp.Blocked {
+		return color
+	}
+	for _, d := range lightDirections {
+		n := lv.Get(x+d[0], y+d[1], z+d[2])
+		if n == nil {
+			continue
+		}
+		for c := range color {
+			if n.Color[c] > 0 && n.Color[c]-1 > color[c] {
+				color[c] = n.Color[c] - 1
+			}
+		}
+	}
+	return color
+}
+
+// randomLightVolume creates a light volume with randomly blocked voxels,
+// some of which emit colored light
+func randomLightVolume(size int, seed int64, density float64) *LightVolume {
+	rnd := rand.New(rand.NewSource(seed))
+	lv := NewLightVolume(size, size+1, size)
+	for z := 0; z < size; z++ {
+		for x := 0; x < size; x++ {
+			for y := 0; y < size; y++ {
+				lv.Block(x, y, z, rnd.Float64() < density)
+				if lv.Get(x, y, z).Blocked && rnd.Intn(40) == 0 {
+					lv.Emit(x, y, z, randomEmission(rnd))
+				}
+			}
+		}
+	}
+	return lv
+}
+
+func randomEmission(rnd *rand.Rand) [3]byte {
+	return [3]byte{
+		byte(rnd.Intn(MaxLightLevel + 1)),
+		byte(rnd.Intn(MaxLightLevel + 1)),
+		byte(rnd.Intn(MaxLightLevel + 1)),
+	}
+}
+
+// copyLight returns a copy of a light volume
+func copyLight(lv *LightVolume) *LightVolume {
+	cp := NewLightVolume(lv.Sx, lv.Sy, lv.Sz)
+	cp.Falloff = lv.Falloff
+	for z := 0; z < lv.Sz; z++ {
+		for x := 0; x < lv.Sx; x++ {
+			copy(cp.Data[z][x], lv.Data[z][x])
+		}
+	}
+	return cp
+}
+
+func assertLightEqual(t *testing.T, expected, actual *LightVolume) {
+	t.Helper()
+	for z := 0; z < expected.Sz; z++ {
+		for x := 0; x < expected.Sx; x++ {
+			for y := 0; y < expected.Sy; y++ {
+				if e, a := expected.Get(x, y, z).V, actual.Get(x, y, z).V; e != a {
+					t.Fatalf("expected light %f at %d,%d,%d, got %f", e, x, y, z, a)
+				}
+				if e, a := expected.Get(x, y, z).Color, actual.Get(x, y, z).Color; e != a {
+					t.Fatalf("expected block light %v at %d,%d,%d, got %v", e, x, y, z, a)
+				}
+			}
+		}
+	}
+}
+
+func TestLightCalculateEquivalence(t *testing.T) {
+	for seed := int64(1); seed <= 8; seed++ {
+		expected := randomLightVolume(12, seed, 0.4)
+		relaxLight(expected)
+
+		actual := randomLightVolume(12, seed, 0.4)
+		actual.Calculate()
+		assertLightEqual(t, expected, actual)
+	}
+}
+
+func TestLightUpdateEquivalence(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	lv := randomLightVolume(12, 42, 0.3)
+	lv.Calculate()
+
+	for i := 0; i < 200; i++ {
+		x, y, z := rnd.Intn(lv.Sx), rnd.Intn(lv.Sy-1), rnd.Intn(lv.Sz)
+		blocked := !lv.Get(x, y, z).Blocked
+		lv.Block(x, y, z, blocked)
+		if blocked && rnd.Intn(4) == 0 {
+			lv.Emit(x, y, z, randomEmission(rnd))
+		} else {
+			lv.Emit(x, y, z, [3]byte{})
+		}
+		lv.Update(x, y, z)
+
+		expected := copyLight(lv)
+		relaxLight(expected)
+		assertLightEqual(t, expected, lv)
+	}
+}
+
+func TestWorldLightUpdateEquivalence(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	world := testWorld(newMemoryStore())
+	size := world.ChunkSize
+	positions := ChunksInRadius(ChunkPos{}, 1)
+	for _, cp := range positions {
+		chunk := NewChunk(size, 0, cp.X, cp.Y, cp.Z)
+		for i := 0; i < size*size*size/3; i++ {
+			chunk.Set(rnd.Intn(size), rnd.Intn(size), rnd.Intn(size), Voxel{Block: ColorBlock, R: 1, G: 1, B: 1})
+		}
+		world.Insert(chunk)
+	}
+
+	for i := 0; i < 100; i++ {
+		x, y, z := rnd.Intn(3*size)-size, rnd.Intn(3*size)-size, rnd.Intn(3*size)-size
+		voxel := EmptyVoxel
+		if world.Voxel(x, y, z) == EmptyVoxel {
+			voxel = Voxel{Block: ColorBlock, R: byte(rnd.Intn(256)), G: byte(rnd.Intn(256)), B: byte(rnd.Intn(256))}
+			blocks := []BlockID{ColorBlock, GlassBlock, LampBlock}
+			voxel.Block = blocks[rnd.Intn(len(blocks))]
+		}
+		world.Set(x, y, z, voxel)
+	}
+
+	// recomputing every chunk from scratch should not change anything
+	incremental := make(map[ChunkPos]*LightVolume)
+	for _, cp := range positions {
+		incremental[cp] = copyLight(world.Chunk(cp).Light)
+	}
+	world.Relight(positions...)
+	for _, cp := range positions {
+		assertLightEqual(t, world.Chunk(cp).Light, incremental[cp])
+	}
+}
+
+func benchmarkTerrainLight(b *testing.B) *LightVolume {
+	return testWorldgen(b, 1, 32).Chunk(0, 0, 0).Light
+}
+
+func BenchmarkLightRelax(b *testing.B) {
+	lv := benchmarkTerrainLight(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		relaxLight(lv)
+	}
+}
+
+func BenchmarkLightCalculate(b *testing.B) {
+	lv := benchmarkTerrainLight(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lv.Calculate()
+	}
+}
+
+func BenchmarkLightUpdate(b *testing.B) {
+	lv := benchmarkTerrainLight(b)
+	x, z := lv.Sx/2, lv.Sz/2
+	y := 0
+	for y < lv.Sy-1 && lv.Get(x, y, z).Blocked {
+		y++
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lv.Block(x, y, z, i%2 == 0)
+		lv.Update(x, y, z)
+	}
+}
@@ -0,0 +1,173 @@
+package game
+
+// ChunkSource looks up loaded chunks by their chunk position.
+// Returns nil if the chunk is not loaded.
+type ChunkSource interface {
+	Chunk(cp ChunkPos) *Chunk
+}
+
+// Neighborhood provides access to the voxels and light values of a chunk and its 26
+// surrounding chunks, using coordinates relative to the center chunk. Coordinates may
+// reach at most one voxel outside the center chunk.
+type Neighborhood struct {
+	Center  *Chunk
+	borders [27]*chunkBorder
+}
+
+// NewNeighborhood captures the chunks surrounding the given center chunk. The voxels and light
+// values of neighboring chunks bordering the center chunk are copied, so that the neighborhood
+// is not affected by later changes to the neighbors. The center chunk is not copied, pass a
+// snapshot if it may be modified while the neighborhood is in use.
+// If source is nil, the center chunk is treated as if it had no neighbors.
+// Since the chunk source is usually not thread safe, the neighborhood should be
+// created on the main thread before it is handed to a background worker.
+func NewNeighborhood(center *Chunk, source ChunkSource) *Neighborhood {
+	n := &Neighborhood{Center: center}
+	if source == nil {
+		return n
+	}
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 && dz == 0 {
+					continue
+				}
+				if chunk := source.Chunk(ChunkPos{center.Cx + dx, center.Cy + dy, center.Cz + dz}); chunk != nil {
+					n.borders[neighborIndex(dx, dy, dz)] = newChunkBorder(chunk, dx, dy, dz)
+				}
+			}
+		}
+	}
+	return n
+}
+
+func neighborIndex(dx, dy, dz int) int {
+	return (dz+1)*9 + (dy+1)*3 + (dx + 1)
+}
+
+// centerIndex is the neighbor index of the center chunk
+var centerIndex = neighborIndex(0, 0, 0)
+
+// locate returns the neighbor index of the chunk containing the given coordinate,
+// and the coordinate local to that chunk
+func (n *Neighborhood) locate(x, y, z int) (int, int, int, int) {
+	size := n.Center.Sx
+	dx, dy, dz := 0, 0, 0
+	if x < 0 {
+		dx, x = -1, x+size
+	} else if x >= size {
+		dx, x = 1, x-size
+	}
+	if y < 0 {
+		dy, y = -1, y+size
+	} else if y >= size {
+		dy, y = 1, y-size
+	}
+	if z < 0 {
+		dz, z = -1, z+size
+	} else if z >= size {
+		dz, z = 1, z-size
+	}
+	return neighborIndex(dx, dy, dz), x, y, z
+}
+
+// At returns the voxel at the given position. Voxels in chunks that are not loaded are empty.
+func (n *Neighborhood) At(x, y, z int) Voxel {
+	i, lx, ly, lz := n.locate(x, y, z)
+	if i == centerIndex {
+		return n.Center.At(lx, ly, lz)
+	}
+	if border := n.borders[i]; border != nil {
+		if j, ok := border.index(lx, ly, lz); ok {
+			return border.voxels[j]
+		}
+	}
+	return EmptyVoxel
+}
+
+// light returns the light voxel at the given position, or false if the position is in a
+// chunk that is not loaded
+func (n *Neighborhood) light(x, y, z int) (*LightVoxel, bool) {
+	i, lx, ly, lz := n.locate(x, y, z)
+	if i == centerIndex {
+		return n.Center.Light.Get(lx, ly, lz), true
+	}
+	if border := n.borders[i]; border != nil {
+		if j, ok := border.index(lx, ly, lz); ok {
+			return &border.light[j], true
+		}
+	}
+	return nil, false
+}
+
+// Brightness returns the light value at the given position. If the neighboring chunk is
+// not loaded, the center chunks light volume is sampled instead.
+func (n *Neighborhood) Brightness(x, y, z int) float32 {
+	lv, loaded := n.light(x, y, z)
+	if !loaded {
+		return n.Center.Light.Brightness(x, y, z)
+	}
+	if lv == nil {
+		return 1
+	}
+	return lv.V
+}
+
+// BlockLight returns the block light levels at the given position.
+// Chunks that are not loaded are dark.
+func (n *Neighborhood) BlockLight(x, y, z int) [3]byte {
+	if lv, _ := n.light(x, y, z); lv != nil {
+		return lv.Color
+	}
+	return [3]byte{}
+}
+
+// chunkBorder is a copy of the voxels and light values of a neighboring chunk
+// that lie within one voxel of the center chunk
+type chunkBorder struct {
+	// min and max hold the inclusive range of local coordinates covered by the copy
+	min, max [3]int
+	voxels   Voxels
+	light    []LightVoxel
+}
+
+// newChunkBorder copies the part of a chunk bordering the center chunk, given the
+// offset of the chunk from the center chunk
+func newChunkBorder(chunk *Chunk, dx, dy, dz int) *chunkBorder {
+	b := &chunkBorder{}
+	size := [3]int{chunk.Sx, chunk.Sy, chunk.Sz}
+	for axis, d := range [3]int{dx, dy, dz} {
+		switch d {
+		case -1:
+			b.min[axis], b.max[axis] = size[axis]-1, size[axis]-1
+		case 1:
+			b.min[axis], b.max[axis] = 0, 0
+		default:
+			b.min[axis], b.max[axis] = 0, size[axis]-1
+		}
+	}
+
+	count := (b.max[0] - b.min[0] + 1) * (b.max[1] - b.min[1] + 1) * (b.max[2] - b.min[2] + 1)
+	b.voxels = make(Voxels, 0, count)
+	b.light = make([]LightVoxel, 0, count)
+	for z := b.min[2]; z <= b.max[2]; z++ {
+		for y := b.min[1]; y <= b.max[1]; y++ {
+			for x := b.min[0]; x <= b.max[0]; x++ {
+				b.voxels = append(b.voxels, chunk.At(x, y, z))
+				b.light = append(b.light, *chunk.Light.Get(x, y, z))
+			}
+		}
+	}
+	return b
+}
+
+// index returns the index of a local coordinate in the copied data,
+// or false if the coordinate is not covered by the copy
+func (b *chunkBorder) index(x, y, z int) (int, bool) {
+	if x < b.min[0] || x > b.max[0] || y < b.min[1] || y > b.max[1] || z < b.min[2] || z > b.max[2] {
+		return 0, false
+	}
+	w := b.max[0] - b.min[0] + 1
+	h := b.max[1] - b.min[1] + 1
+	return ((z-b.min[2])*h+(y-b.min[1]))*w + (x - b.min[0]), true
+}
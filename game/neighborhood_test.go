@@ -0,0 +1,101 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func solidChunk(size, cx, cy, cz int) *Chunk {
+	chunk := NewChunk(size, 0, cx, cy, cz)
+	for z := 0; z < size; z++ {
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				chunk.Set(x, y, z, Voxel{Block: ColorBlock, R: 255, G: 255, B: 255})
+			}
+		}
+	}
+	return chunk
+}
+
+func TestNeighborhoodSeams(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	size := world.ChunkSize
+	center := solidChunk(size, 0, 0, 0)
+	world.Insert(center)
+
+	faces := func() int {
+		return len(computeQuads(NewNeighborhood(center, world)))
+	}
+	if n := faces(); n != 6*size*size {
+		t.Errorf("expected %d faces for an isolated chunk, got %d", 6*size*size, n)
+	}
+
+	world.Insert(solidChunk(size, 1, 0, 0))
+	if n := faces(); n != 5*size*size {
+		t.Errorf("expected %d faces with one neighbor, got %d", 5*size*size, n)
+	}
+
+	for _, cp := range neighborsOf(ChunkPos{}, false) {
+		world.Insert(solidChunk(size, cp.X, cp.Y, cp.Z))
+	}
+	if n := faces(); n != 0 {
+		t.Errorf("expected no faces for a buried chunk, got %d", n)
+	}
+}
+
+func TestNeighborhoodNegativeOffsets(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	size := world.ChunkSize
+	center := NewChunk(size, 0, 0, 0, 0)
+	world.Insert(center)
+	world.Insert(solidChunk(size, -1, -1, 0))
+
+	view := NewNeighborhood(center, world)
+	if v := view.At(-1, -1, 0); v == EmptyVoxel {
+		t.Errorf("expected voxel from diagonal neighbor")
+	}
+	if v := view.At(-1, 0, 0); v != EmptyVoxel {
+		t.Errorf("expected empty voxel from unloaded neighbor, got %v", v)
+	}
+}
+
+func TestWorldChunkEvents(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	world.AddChunk(0, 0, 0)
+
+	var events []ChunkEvent
+	world.Subscribe(func(e ChunkEvent) {
+		if e.Type != ChunkRelit {
+			events = append(events, e)
+		}
+	})
+
+	cases := []struct {
+		Pos     WorldPos
+		Touched []ChunkPos
+	}{
+		{WorldPos{1, 1, 1}, []ChunkPos{{0, 0, 0}}},
+		{WorldPos{3, 1, 1}, []ChunkPos{{0, 0, 0}, {1, 0, 0}}},
+		{WorldPos{0, 0, 2}, []ChunkPos{{-1, -1, 0}, {0, -1, 0}, {-1, 0, 0}, {0, 0, 0}}},
+	}
+	for _, c := range cases {
+		events = nil
+		world.Set(c.Pos.X, c.Pos.Y, c.Pos.Z, Voxel{Block: ColorBlock, R: 1, G: 2, B: 3})
+		if len(events) != 1 || events[0].Type != ChunkModified {
+			t.Fatalf("expected one modified event for %v, got %v", c.Pos, events)
+		}
+		if !reflect.DeepEqual(events[0].Touched, c.Touched) {
+			t.Errorf("expected %v to touch %v, got %v", c.Pos, c.Touched, events[0].Touched)
+		}
+	}
+
+	events = nil
+	world.AddChunk(1, 0, 0)
+	world.Unload(ChunkPos{1, 0, 0})
+	if len(events) != 2 || events[0].Type != ChunkLoaded || events[1].Type != ChunkUnloaded {
+		t.Fatalf("expected load and unload events, got %v", events)
+	}
+	if len(events[0].Touched) != 27 || len(events[1].Touched) != 26 {
+		t.Errorf("expected 27 and 26 touched chunks, got %d and %d", len(events[0].Touched), len(events[1].Touched))
+	}
+}
@@ -0,0 +1,210 @@
+package game
+
+// PalettedVoxels stores a fixed number of voxels as bit-packed indices into a
+// palette of distinct voxel values. The number of bits per index grows as new
+// values are added. When every voxel holds the same value, the index data is
+// dropped entirely and only the single palette entry is kept.
+type PalettedVoxels struct {
+	size    int
+	bits    uint
+	palette Voxels
+	refs    []int
+	data    []uint64
+}
+
+// NewPalettedVoxels creates a paletted voxel storage of the given length,
+// with every voxel set to EmptyVoxel.
+func NewPalettedVoxels(size int) *PalettedVoxels {
+	pv := &PalettedVoxels{size: size}
+	pv.Fill(EmptyVoxel)
+	return pv
+}
+
+// Len returns the number of voxels in the storage
+func (pv *PalettedVoxels) Len() int { return pv.size }
+
+// Bits returns the number of bits used per voxel index
+func (pv *PalettedVoxels) Bits() int { return int(pv.bits) }
+
+// PaletteSize returns the number of palette entries, including unused ones
+func (pv *PalettedVoxels) PaletteSize() int { return len(pv.palette) }
+
+// Uniform returns true if all voxels hold the same value
+func (pv *PalettedVoxels) Uniform() bool { return pv.bits == 0 }
+
+// Bytes returns the approximate number of bytes used to hold the voxel data
+func (pv *PalettedVoxels) Bytes() int {
+	return 8*len(pv.data) + 3*len(pv.palette) + 8*len(pv.refs)
+}
+
+// Get returns the voxel at index i
+func (pv *PalettedVoxels) Get(i int) Voxel {
+	return pv.palette[pv.index(i)]
+}
+
+// Set the voxel at index i
+func (pv *PalettedVoxels) Set(i int, voxel Voxel) {
+	old := pv.index(i)
+	if pv.palette[old] == voxel {
+		return
+	}
+
+	idx := pv.lookup(voxel)
+	pv.refs[old]--
+	pv.refs[idx]++
+
+	if pv.refs[idx] == pv.size {
+		// every voxel now has the same value
+		pv.Fill(voxel)
+		return
+	}
+
+	pv.setIndex(i, idx)
+}
+
+// Fill sets every voxel to the given value, collapsing the storage to a single palette entry.
+func (pv *PalettedVoxels) Fill(voxel Voxel) {
+	pv.bits = 0
+	pv.data = nil
+	pv.palette = Voxels{voxel}
+	pv.refs = []int{pv.size}
+}
+
+// Clone returns a copy of the storage that shares no memory with the original
+func (pv *PalettedVoxels) Clone() *PalettedVoxels {
+	return &PalettedVoxels{
+		size:    pv.size,
+		bits:    pv.bits,
+		palette: append(Voxels(nil), pv.palette...),
+		refs:    append([]int(nil), pv.refs...),
+		data:    append([]uint64(nil), pv.data...),
+	}
+}
+
+// Voxels returns a flat copy of all voxels
+func (pv *PalettedVoxels) Voxels() Voxels {
+	voxels := make(Voxels, pv.size)
+	for i := range voxels {
+		voxels[i] = pv.Get(i)
+	}
+	return voxels
+}
+
+// Load replaces the storage contents with the given flat voxel slice
+func (pv *PalettedVoxels) Load(voxels Voxels) {
+	pv.size = len(voxels)
+	if pv.size == 0 {
+		pv.Fill(EmptyVoxel)
+		return
+	}
+	pv.Fill(voxels[0])
+	for i, voxel := range voxels {
+		pv.Set(i, voxel)
+	}
+	pv.Compact()
+}
+
+// Compact removes unused palette entries and shrinks the index width to the smallest possible size.
+func (pv *PalettedVoxels) Compact() {
+	remap := make([]int, len(pv.palette))
+	palette := make(Voxels, 0, len(pv.palette))
+	refs := make([]int, 0, len(pv.palette))
+	for idx, count := range pv.refs {
+		if count == 0 {
+			continue
+		}
+		remap[idx] = len(palette)
+		palette = append(palette, pv.palette[idx])
+		refs = append(refs, count)
+	}
+
+	if len(palette) == 1 {
+		pv.Fill(palette[0])
+		return
+	}
+
+	indices := make([]int, pv.size)
+	for i := range indices {
+		indices[i] = remap[pv.index(i)]
+	}
+
+	pv.palette = palette
+	pv.refs = refs
+	pv.pack(bitsFor(len(palette)), indices)
+}
+
+// index returns the palette index of voxel i
+func (pv *PalettedVoxels) index(i int) int {
+	if pv.bits == 0 {
+		return 0
+	}
+	per := 64 / pv.bits
+	word := pv.data[i/int(per)]
+	shift := uint(i%int(per)) * pv.bits
+	return int((word >> shift) & (1<<pv.bits - 1))
+}
+
+// setIndex writes the palette index of voxel i
+func (pv *PalettedVoxels) setIndex(i, idx int) {
+	per := 64 / pv.bits
+	w := i / int(per)
+	shift := uint(i%int(per)) * pv.bits
+	mask := uint64(1<<pv.bits-1) << shift
+	pv.data[w] = (pv.data[w] &^ mask) | (uint64(idx) << shift)
+}
+
+// lookup returns the palette index of a voxel value, adding it to the palette if required.
+func (pv *PalettedVoxels) lookup(voxel Voxel) int {
+	free := -1
+	for idx, entry := range pv.palette {
+		if pv.refs[idx] == 0 {
+			if free < 0 {
+				free = idx
+			}
+			continue
+		}
+		if entry == voxel {
+			return idx
+		}
+	}
+
+	// reuse an unused palette slot if possible
+	if free >= 0 {
+		pv.palette[free] = voxel
+		return free
+	}
+
+	idx := len(pv.palette)
+	pv.palette = append(pv.palette, voxel)
+	pv.refs = append(pv.refs, 0)
+
+	// grow index width if the new index does not fit
+	if bits := bitsFor(len(pv.palette)); bits > pv.bits {
+		indices := make([]int, pv.size)
+		for i := range indices {
+			indices[i] = pv.index(i)
+		}
+		pv.pack(bits, indices)
+	}
+
+	return idx
+}
+
+// pack rewrites the index data using the given number of bits per index
+func (pv *PalettedVoxels) pack(bits uint, indices []int) {
+	per := int(64 / bits)
+	pv.bits = bits
+	pv.data = make([]uint64, (pv.size+per-1)/per)
+	for i, idx := range indices {
+		pv.setIndex(i, idx)
+	}
+}
+
+// bitsFor returns the number of bits required to address n palette entries
+func bitsFor(n int) uint {
+	bits := uint(1)
+	for 1<<bits < n {
+		bits++
+	}
+	return bits
+}
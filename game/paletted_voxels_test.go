@@ -0,0 +1,100 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPalettedVoxelsGrowth(t *testing.T) {
+	pv := NewPalettedVoxels(4096)
+	if !pv.Uniform() || pv.Bits() != 0 {
+		t.Fatal("expected new storage to be uniform")
+	}
+
+	for i := 0; i < 5; i++ {
+		pv.Set(i, Voxel{Block: ColorBlock, R: byte(i + 1)})
+	}
+	if pv.Bits() != 3 {
+		t.Errorf("expected 3 bits per index for 6 colors, was %d", pv.Bits())
+	}
+	for i := 0; i < 5; i++ {
+		if v := pv.Get(i); v.R != byte(i+1) {
+			t.Errorf("expected voxel %d to survive repacking, was %v", i, v)
+		}
+	}
+	if v := pv.Get(5); v != EmptyVoxel {
+		t.Errorf("expected untouched voxel to be empty, was %v", v)
+	}
+}
+
+func TestPalettedVoxelsUniform(t *testing.T) {
+	pv := NewPalettedVoxels(64)
+	stone := Voxel{Block: ColorBlock, R: 100, G: 100, B: 100}
+	for i := 0; i < pv.Len(); i++ {
+		pv.Set(i, stone)
+	}
+	if !pv.Uniform() || pv.PaletteSize() != 1 {
+		t.Errorf("expected filled storage to collapse to a single value, had %d entries", pv.PaletteSize())
+	}
+	if pv.Get(17) != stone {
+		t.Error("expected uniform storage to return the fill value")
+	}
+
+	pv.Set(3, EmptyVoxel)
+	pv.Set(3, stone)
+	if !pv.Uniform() {
+		t.Error("expected storage to collapse again after restoring the fill value")
+	}
+}
+
+func TestPalettedVoxelsCompact(t *testing.T) {
+	pv := NewPalettedVoxels(256)
+	for i := 0; i < 20; i++ {
+		pv.Set(i, Voxel{Block: ColorBlock, G: byte(i + 1)})
+	}
+	for i := 2; i < 20; i++ {
+		pv.Set(i, EmptyVoxel)
+	}
+	pv.Compact()
+	if pv.PaletteSize() != 3 || pv.Bits() != 2 {
+		t.Errorf("expected 3 entries at 2 bits after compaction, was %d at %d bits", pv.PaletteSize(), pv.Bits())
+	}
+	if pv.Get(0).G != 1 || pv.Get(1).G != 2 || pv.Get(2) != EmptyVoxel {
+		t.Error("compaction changed voxel values")
+	}
+}
+
+func TestPalettedVoxelsEquivalence(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	colors := Voxels{EmptyVoxel}
+	for i := 0; i < 40; i++ {
+		colors = append(colors, Voxel{Block: ColorBlock, R: byte(rnd.Intn(256)), G: byte(rnd.Intn(256)), B: byte(rnd.Intn(256))})
+	}
+
+	size := 16 * 16 * 16
+	flat := make(Voxels, size)
+	pv := NewPalettedVoxels(size)
+	for n := 0; n < 20000; n++ {
+		i := rnd.Intn(size)
+		v := colors[rnd.Intn(len(colors))]
+		flat[i] = v
+		pv.Set(i, v)
+	}
+
+	for i := range flat {
+		if pv.Get(i) != flat[i] {
+			t.Fatalf("voxel %d differs: expected %v, was %v", i, flat[i], pv.Get(i))
+		}
+	}
+
+	loaded := NewPalettedVoxels(0)
+	loaded.Load(pv.Voxels())
+	for i := range flat {
+		if loaded.Get(i) != flat[i] {
+			t.Fatalf("loaded voxel %d differs", i)
+		}
+	}
+	if loaded.Bytes() >= 3*size {
+		t.Errorf("expected paletted storage to use less memory than a flat slice, used %d bytes", loaded.Bytes())
+	}
+}
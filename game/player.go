@@ -2,11 +2,15 @@ package game
 
 import (
 	"github.com/johanhenriksson/goworld/engine"
-	"github.com/johanhenriksson/goworld/engine/keys"
 	"github.com/johanhenriksson/goworld/math/vec3"
 )
 
-type CollisionCheck func(*Player, vec3.T) (bool, vec3.T)
+// PlayerTickRate is the number of player physics steps per second
+const PlayerTickRate = 60
+
+// MaxPlayerCatchUp is the largest number of physics steps taken by a single call to Update.
+// Any time beyond it, such as after a long stall, is dropped.
+const MaxPlayerCatchUp = PlayerTickRate / 4
 
 type Player struct {
 	*engine.Camera
@@ -21,15 +25,34 @@ type Player struct {
 	Flying      bool
 	Grounded    bool
 
-	collide  CollisionCheck
+	// Input returns the movement requested for each update. Defaults to the keyboard.
+	Input func() PlayerInput
+
+	// Surface returns the block type below the given feet position, if set.
+	// Its friction scales the ground friction of the player.
+	Surface func(feet vec3.T) *BlockType
+
+	// Collider moves the player through the terrain
+	Collider *BoxCollider
+
 	position vec3.T
 	velocity vec3.T
+
+	// tick and time track the physics steps taken so far
+	tick uint64
+	time float64
+
+	// toggleFly is set when a ToggleFly input has not yet been applied by a physics step
+	toggleFly bool
 }
 
-func NewPlayer(camera *engine.Camera, collide CollisionCheck) *Player {
+// NewPlayer creates a first person player controlling the camera. Solid returns true for
+// voxels the player collides with.
+func NewPlayer(camera *engine.Camera, solid func(x, y, z int) bool) *Player {
 	p := &Player{
 		Camera:      camera,
-		collide:     collide,
+		Collider:    NewPlayerCollider(solid),
+		Input:       KeyboardInput,
 		Gravity:     float32(53),
 		Speed:       float32(60),
 		Airspeed:    float32(33),
@@ -43,46 +66,47 @@ func NewPlayer(camera *engine.Camera, collide CollisionCheck) *Player {
 	return p
 }
 
+// Update reads the player input and advances the player by dt seconds. Physics are stepped at a
+// fixed PlayerTickRate, so the movement only depends on the total time, not on the frame rate.
+// At most MaxPlayerCatchUp steps are taken per update, the rest of the time is dropped.
+// Toggles are held until the next physics step, so that they are not lost in updates without one.
 func (p *Player) Update(dt float32) {
-	move := vec3.Zero
-	moving := false
-	if keys.Down(keys.W) && !keys.Down(keys.S) {
-		move.Z += 1.0
-		moving = true
+	input := p.Input()
+	if input.ToggleFly {
+		p.toggleFly = true
 	}
-	if keys.Down(keys.S) && !keys.Down(keys.W) {
-		move.Z -= 1.0
-		moving = true
+	p.time += float64(dt)
+	due := uint64(p.time*PlayerTickRate + tickEpsilon)
+	if due > p.tick+MaxPlayerCatchUp {
+		due = p.tick + MaxPlayerCatchUp
+		p.time = float64(due) / PlayerTickRate
 	}
-	if keys.Down(keys.A) && !keys.Down(keys.D) {
-		move.X -= 1.0
-		moving = true
-	}
-	if keys.Down(keys.D) && !keys.Down(keys.A) {
-		move.X += 1.0
-		moving = true
-	}
-	if p.Flying && keys.Down(keys.Q) && !keys.Down(keys.E) {
-		move.Y -= 1.0
-		moving = true
-	}
-	if p.Flying && keys.Down(keys.E) && !keys.Down(keys.Q) {
-		move.Y += 1.0
-		moving = true
+	for p.tick < due {
+		// toggles only apply once
+		input.ToggleFly = p.toggleFly
+		p.toggleFly = false
+
+		p.Step(input, 1.0/PlayerTickRate)
+		p.tick++
 	}
-	if keys.Pressed(keys.V) {
+}
+
+// Step advances the player physics by dt seconds, moving as requested by the input.
+// The outcome only depends on the input and the current state of the player.
+func (p *Player) Step(input PlayerInput, dt float32) {
+	if input.ToggleFly {
 		p.Flying = !p.Flying
 	}
 
-	if moving {
-		right := p.Camera.Right().Scaled(move.X)
-		forward := p.Camera.Forward().Scaled(move.Z)
-		up := vec3.New(0, move.Y, 0)
+	move := vec3.Zero
+	if input.Move.X != 0 || input.Move.Z != 0 || (p.Flying && input.Move.Y != 0) {
+		right := p.Camera.Right().Scaled(input.Move.X)
+		forward := p.Camera.Forward().Scaled(input.Move.Z)
 
 		move = right.Add(forward)
 		move.Y = 0 // remove y component
 		if p.Flying {
-			move = move.Add(up)
+			move.Y = input.Move.Y
 		}
 		move.Normalize()
 	}
@@ -92,7 +116,7 @@ func (p *Player) Update(dt float32) {
 		move.Scale(p.Airspeed)
 	}
 
-	if keys.Down(keys.LeftShift) {
+	if input.Sprint {
 		move.Scale(2)
 	}
 
@@ -101,7 +125,7 @@ func (p *Player) Update(dt float32) {
 
 	// friction
 	if p.Grounded {
-		p.velocity = p.velocity.Mul(p.Friction)
+		p.velocity = p.velocity.Mul(p.groundFriction())
 	} else {
 		p.velocity = p.velocity.Mul(p.AirFriction)
 	}
@@ -114,43 +138,41 @@ func (p *Player) Update(dt float32) {
 		p.velocity.Y *= p.AirFriction.X
 	}
 
+	// move through the terrain. ledges are only climbed while walking on the ground
 	step := p.velocity.Scaled(dt)
+	position, collision := p.Collider.Move(p.position, step, p.Grounded && !p.Flying)
+	p.position = position
+	p.Grounded = collision.Grounded
 
-	// apply movement in Y
-	p.position.Y += step.Y
-	step.Y = 0
-
-	// ground collision
-	if collides, point := p.collide(p, p.position); collides {
-		p.position.Y = point.Y
+	// stop at obstacles
+	if collision.Ceiling && p.velocity.Y > 0 {
 		p.velocity.Y = 0
-		p.Grounded = true
-	} else {
-		p.Grounded = false
 	}
-
-	// jumping
-	if p.Grounded && keys.Down(keys.Space) {
-		p.velocity.Y += p.JumpForce * p.Gravity
+	if collision.Grounded && p.velocity.Y < 0 {
+		p.velocity.Y = 0
 	}
-
-	// x collision
-	xstep := p.position.Add(vec3.New(step.X, 0, 0))
-	// if p.world.HeightAt(xstep) > p.position.Y {
-	if collides, _ := p.collide(p, xstep); collides {
-		step.X = 0
+	if collision.WallX {
+		p.velocity.X = 0
 	}
-
-	// z collision
-	zstep := p.position.Add(vec3.New(0, 0, step.Z))
-	// if p.world.HeightAt(zstep) > p.position.Y {
-	if collides, _ := p.collide(p, zstep); collides {
-		step.Z = 0
+	if collision.WallZ {
+		p.velocity.Z = 0
 	}
 
-	// add horizontal movement
-	p.position = p.position.Add(step)
+	// jumping
+	if p.Grounded && input.Jump {
+		p.velocity.Y += p.JumpForce * p.Gravity
+	}
 
 	// update camera position
 	p.Camera.SetPosition(p.position.Add(p.CamHeight))
 }
+
+// groundFriction returns the friction of the ground below the player
+func (p *Player) groundFriction() vec3.T {
+	if p.Surface == nil {
+		return p.Friction
+	}
+	// scale the speed lost to friction by the friction of the block
+	f := p.Surface(p.position).Friction
+	return vec3.One.Sub(vec3.One.Sub(p.Friction).Scaled(f))
+}
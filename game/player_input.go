@@ -0,0 +1,47 @@
+package game
+
+import (
+	"github.com/johanhenriksson/goworld/engine/keys"
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// PlayerInput is the movement requested from a player for a single update,
+// by the keyboard or any other controller
+type PlayerInput struct {
+	// Move is the requested direction relative to the camera, with X to the right and Z forward.
+	// Y moves straight up or down, but only while flying.
+	Move vec3.T
+
+	// Jump is true while the player should jump whenever it is on the ground
+	Jump bool
+
+	// Sprint doubles the movement speed
+	Sprint bool
+
+	// ToggleFly switches between walking and flying
+	ToggleFly bool
+}
+
+// KeyboardInput reads player input from the keyboard
+func KeyboardInput() PlayerInput {
+	input := PlayerInput{
+		Jump:      keys.Down(keys.Space),
+		Sprint:    keys.Down(keys.LeftShift),
+		ToggleFly: keys.Pressed(keys.V),
+	}
+	input.Move.X = keyAxis(keys.A, keys.D)
+	input.Move.Y = keyAxis(keys.Q, keys.E)
+	input.Move.Z = keyAxis(keys.S, keys.W)
+	return input
+}
+
+// keyAxis returns -1 or 1 if either the negative or the positive key is held, or 0 if none or both are
+func keyAxis(negative, positive keys.Code) float32 {
+	switch {
+	case keys.Down(negative) && !keys.Down(positive):
+		return -1
+	case keys.Down(positive) && !keys.Down(negative):
+		return 1
+	}
+	return 0
+}
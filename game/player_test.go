@@ -0,0 +1,152 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/johanhenriksson/goworld/engine"
+	"github.com/johanhenriksson/goworld/math"
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/render"
+)
+
+// testPlayer creates a player standing on a flat floor, facing along the z axis
+func testPlayer(terrain testTerrain) *Player {
+	camera := engine.CreateCamera(&render.FrameBuffer{Width: 4, Height: 3}, vec3.New(0.5, 2.75, 0.5), 55, 0.1, 100)
+	player := NewPlayer(camera, terrain.Solid)
+	player.Input = func() PlayerInput { return PlayerInput{} }
+	player.Step(PlayerInput{}, 1.0/PlayerTickRate)
+	return player
+}
+
+func TestPlayerCollision(t *testing.T) {
+	terrain := testTerrain{}
+	for x := -2; x <= 2; x++ {
+		for z := -2; z <= 2; z++ {
+			terrain[WorldPos{x, 3, z}] = true
+		}
+	}
+	player := testPlayer(terrain)
+	if !player.Grounded || !math.EqualThreshold(player.Camera.Position().Y, 2.75, 0.01) {
+		t.Fatalf("expected the player to stand on the ground, camera at %v", player.Camera.Position())
+	}
+
+	// jump into the ceiling
+	player.Step(PlayerInput{Jump: true}, 1.0/PlayerTickRate)
+	for i := 0; i < 5; i++ {
+		player.Step(PlayerInput{}, 1.0/PlayerTickRate)
+		if top := player.position.Y + player.Collider.Height; top > 3 {
+			t.Fatalf("expected the player to stay below the ceiling, top at %f", top)
+		}
+	}
+	if player.velocity.Y > 0 {
+		t.Errorf("expected the ceiling to stop upward movement, velocity %v", player.velocity)
+	}
+}
+
+func TestPlayerInput(t *testing.T) {
+	walk := func(input PlayerInput) vec3.T {
+		player := testPlayer(testTerrain{})
+		start := player.position
+		for i := 0; i < PlayerTickRate; i++ {
+			player.Step(input, 1.0/PlayerTickRate)
+		}
+		if !player.Grounded {
+			t.Errorf("expected the player to stay on the ground with input %+v", input)
+		}
+		return player.position.Sub(start)
+	}
+
+	forward := walk(PlayerInput{Move: vec3.New(0, 0, 1)})
+	direction := testPlayer(testTerrain{}).Camera.Forward()
+	direction.Y = 0
+	if d := forward.Normalized().Sub(direction.Normalized()); forward.Length() < 1 || d.Length() > 1e-3 {
+		t.Errorf("expected the player to walk along the camera direction %v, moved %v", direction, forward)
+	}
+	if sprint := walk(PlayerInput{Move: vec3.New(0, 0, 1), Sprint: true}); sprint.Length() <= forward.Length()*1.5 {
+		t.Errorf("expected sprinting to move further, moved %v instead of %v", sprint, forward)
+	}
+	if up := walk(PlayerInput{Move: vec3.New(0, 1, 0)}); up != vec3.Zero {
+		t.Errorf("expected vertical input to be ignored while walking, moved %v", up)
+	}
+
+	// jumping leaves the ground
+	player := testPlayer(testTerrain{})
+	player.Step(PlayerInput{Jump: true}, 1.0/PlayerTickRate)
+	player.Step(PlayerInput{}, 1.0/PlayerTickRate)
+	if player.Grounded || player.position.Y <= 1 {
+		t.Errorf("expected the player to jump, at %v", player.position)
+	}
+}
+
+func TestPlayerFrameRateIndependence(t *testing.T) {
+	simulate := func(frames int) *Player {
+		player := testPlayer(testTerrain{WorldPos{1, 1, 3}: true, WorldPos{3, 1, 2}: true})
+		player.Input = func() PlayerInput {
+			// walk diagonally over the blocks, jumping whenever possible
+			return PlayerInput{Move: vec3.New(1, 0, 1), Jump: true}
+		}
+		for i := 0; i < frames; i++ {
+			player.Update(2 / float32(frames))
+		}
+		return player
+	}
+
+	expected := simulate(120)
+	if expected.position.Sub(vec3.New(0.5, 1, 0.5)).Length() < 2 {
+		t.Fatalf("expected the player to move, ended at %v", expected.position)
+	}
+	for _, frames := range []int{120, 30, 8} {
+		player := simulate(frames)
+		if player.tick != 2*PlayerTickRate {
+			t.Errorf("%d frames: expected %d ticks, got %d", frames, 2*PlayerTickRate, player.tick)
+		}
+		if player.position != expected.position || player.velocity != expected.velocity {
+			t.Errorf("%d frames: expected to end at %v, got %v", frames, expected.position, player.position)
+		}
+	}
+}
+
+func TestPlayerCatchUpLimit(t *testing.T) {
+	player := testPlayer(testTerrain{})
+
+	// a long stall only steps a limited number of times, and the rest is dropped
+	player.Update(10)
+	if player.tick != MaxPlayerCatchUp {
+		t.Fatalf("expected %d steps after a stall, got %d", MaxPlayerCatchUp, player.tick)
+	}
+	player.Update(1.0 / PlayerTickRate)
+	if player.tick != MaxPlayerCatchUp+1 {
+		t.Errorf("expected the dropped time not to be stepped later, got %d steps", player.tick)
+	}
+}
+
+func TestPlayerToggleFly(t *testing.T) {
+	player := testPlayer(testTerrain{})
+	player.Input = func() PlayerInput { return PlayerInput{ToggleFly: true, Move: vec3.New(0, 1, 0)} }
+
+	// several ticks in one update only toggle once
+	player.Update(4.0 / PlayerTickRate)
+	if !player.Flying {
+		t.Fatal("expected the player to fly")
+	}
+	if player.position.Y <= 1 {
+		t.Errorf("expected vertical input to lift a flying player, at %v", player.position)
+	}
+	player.Update(1.0 / PlayerTickRate)
+	if player.Flying {
+		t.Error("expected the next update to toggle flying off")
+	}
+
+	// at 144 fps, a toggle held for a single frame is applied by the next physics step
+	player = testPlayer(testTerrain{})
+	toggle := true
+	player.Input = func() PlayerInput { return PlayerInput{ToggleFly: toggle} }
+	player.Update(1.0 / 144)
+	toggle = false
+	for i := 0; i < 10; i++ {
+		player.Update(1.0 / 144)
+	}
+	if !player.Flying {
+		t.Error("expected a single frame toggle to make the player fly")
+	}
+}
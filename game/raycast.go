@@ -0,0 +1,102 @@
+package game
+
+import (
+	"github.com/johanhenriksson/goworld/math"
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
+)
+
+// RaycastHit describes the first voxel hit by a ray
+type RaycastHit struct {
+	// Position of the hit voxel
+	Position WorldPos
+	Voxel    Voxel
+
+	// Normal of the face the ray entered the hit voxel through.
+	// Zero if the ray started inside the voxel.
+	Normal vec3.T
+
+	// Distance along the ray to the point where it entered the hit voxel
+	Distance float32
+
+	// Previous is the last empty cell traversed before the hit, which is where a voxel placed
+	// against the hit face ends up. Equal to Position if the ray started inside the voxel.
+	Previous WorldPos
+}
+
+// Point returns the point where the ray entered the hit voxel
+func (h RaycastHit) Point(ray physics.Ray) vec3.T {
+	return ray.Origin.Add(ray.Dir.Normalized().Scaled(h.Distance))
+}
+
+// Raycast traverses the world along a ray and returns the first non-air voxel within maxDistance.
+// Voxels in chunks that are not loaded are sampled from the chunk provider.
+func (w *World) Raycast(ray physics.Ray, maxDistance float32) (RaycastHit, bool) {
+	return raycast(ray, maxDistance, w.Voxel)
+}
+
+// Raycast traverses the chunk along a ray in local chunk coordinates and returns the first
+// non-air voxel within maxDistance. Hit positions are local to the chunk.
+func (c *Chunk) Raycast(ray physics.Ray, maxDistance float32) (RaycastHit, bool) {
+	return raycast(ray, maxDistance, c.At)
+}
+
+// raycast visits every cell intersected by the ray in order, using the voxel traversal algorithm
+// by Amanatides & Woo, until a non-air voxel is found or the ray exceeds maxDistance.
+func raycast(ray physics.Ray, maxDistance float32, voxel func(x, y, z int) Voxel) (RaycastHit, bool) {
+	if ray.Dir.LengthSqr() == 0 {
+		return RaycastHit{}, false
+	}
+	origin := ray.Origin.Slice()
+	dir := ray.Dir.Normalized().Slice()
+	start := WorldPosAt(ray.Origin)
+	cell := [3]int{start.X, start.Y, start.Z}
+
+	// step is the direction of traversal along each axis, tMax the distance along the ray to the
+	// next cell boundary and tDelta the distance between two boundaries on each axis
+	step := [3]int{}
+	tMax := [3]float32{math.InfPos, math.InfPos, math.InfPos}
+	tDelta := [3]float32{math.InfPos, math.InfPos, math.InfPos}
+	for i := 0; i < 3; i++ {
+		if dir[i] > 0 {
+			step[i] = 1
+			tDelta[i] = 1 / dir[i]
+			tMax[i] = (float32(cell[i]+1) - origin[i]) / dir[i]
+		} else if dir[i] < 0 {
+			step[i] = -1
+			tDelta[i] = -1 / dir[i]
+			tMax[i] = (float32(cell[i]) - origin[i]) / dir[i]
+		}
+	}
+
+	previous := cell
+	normal := [3]float32{}
+	distance := float32(0)
+	for distance <= maxDistance {
+		if v := voxel(cell[0], cell[1], cell[2]); v.Block != AirBlock {
+			return RaycastHit{
+				Position: WorldPos{cell[0], cell[1], cell[2]},
+				Voxel:    v,
+				Normal:   vec3.New(normal[0], normal[1], normal[2]),
+				Distance: distance,
+				Previous: WorldPos{previous[0], previous[1], previous[2]},
+			}, true
+		}
+
+		// step across the closest cell boundary
+		axis := 0
+		if tMax[1] < tMax[axis] {
+			axis = 1
+		}
+		if tMax[2] < tMax[axis] {
+			axis = 2
+		}
+		previous = cell
+		distance = tMax[axis]
+		cell[axis] += step[axis]
+		tMax[axis] += tDelta[axis]
+		normal = [3]float32{}
+		normal[axis] = float32(-step[axis])
+	}
+	return RaycastHit{}, false
+}
@@ -0,0 +1,109 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
+)
+
+func TestChunkRaycast(t *testing.T) {
+	chunk := NewChunk(8, 0, 0, 0, 0)
+	red := Voxel{Block: ColorBlock, R: 255}
+	chunk.Set(5, 2, 3, red)
+
+	ray := physics.Ray{Origin: vec3.New(0.5, 2.5, 3.5), Dir: vec3.New(1, 0, 0)}
+	hit, exists := chunk.Raycast(ray, 10)
+	if !exists {
+		t.Fatal("expected the ray to hit the voxel")
+	}
+	expected := RaycastHit{
+		Position: WorldPos{5, 2, 3},
+		Voxel:    red,
+		Normal:   vec3.New(-1, 0, 0),
+		Distance: 4.5,
+		Previous: WorldPos{4, 2, 3},
+	}
+	if hit != expected {
+		t.Errorf("expected %+v, got %+v", expected, hit)
+	}
+	if p := hit.Point(ray); p != vec3.New(5, 2.5, 3.5) {
+		t.Errorf("expected the ray to enter the voxel at its face, got %v", p)
+	}
+
+	if _, exists := chunk.Raycast(ray, 4); exists {
+		t.Error("expected voxels beyond the max distance to be missed")
+	}
+
+	// starting inside a voxel hits it immediately
+	inside := physics.Ray{Origin: vec3.New(5.2, 2.9, 3.1), Dir: vec3.New(0, 1, 0)}
+	if hit, exists := chunk.Raycast(inside, 10); !exists || hit.Distance != 0 || hit.Normal != vec3.Zero || hit.Previous != hit.Position {
+		t.Errorf("expected a hit at the ray origin, got %+v", hit)
+	}
+}
+
+func TestRaycastMatchesMarching(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	chunk := NewChunk(16, 0, 0, 0, 0)
+	for i := 0; i < 80; i++ {
+		chunk.Set(rnd.Intn(16), rnd.Intn(16), rnd.Intn(16), Voxel{Block: ColorBlock, R: byte(i)})
+	}
+
+	for i := 0; i < 500; i++ {
+		origin := vec3.New(rnd.Float32()*16, rnd.Float32()*16, rnd.Float32()*16)
+		dir := vec3.New(rnd.Float32()*2-1, rnd.Float32()*2-1, rnd.Float32()*2-1).Normalized()
+		ray := physics.Ray{Origin: origin, Dir: dir}
+		hit, exists := chunk.Raycast(ray, 30)
+
+		// march along the ray in tiny steps to find the first solid cell
+		var marched *WorldPos
+		for d := float32(0); d <= 30; d += 0.001 {
+			p := WorldPosAt(origin.Add(dir.Scaled(d)))
+			if chunk.At(p.X, p.Y, p.Z) != EmptyVoxel {
+				marched = &p
+				break
+			}
+		}
+
+		if marched == nil {
+			if exists {
+				t.Errorf("ray %d: expected a miss, got %+v", i, hit)
+			}
+			continue
+		}
+		if !exists {
+			t.Errorf("ray %d: expected a hit at %v", i, *marched)
+			continue
+		}
+		if hit.Position != *marched {
+			t.Errorf("ray %d: expected a hit at %v, got %v", i, *marched, hit.Position)
+			continue
+		}
+		if hit.Normal != vec3.Zero {
+			if n := hit.Previous.Vec3().Sub(hit.Position.Vec3()); n != hit.Normal {
+				t.Errorf("ray %d: expected the previous cell %v to lie along the normal %v", i, hit.Previous, hit.Normal)
+			}
+		}
+	}
+}
+
+func TestWorldRaycastAcrossChunks(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	for x := -2; x <= 1; x++ {
+		world.AddChunk(x, 0, 0)
+	}
+	world.Set(-6, 1, 2, Voxel{Block: ColorBlock, G: 255})
+
+	ray := physics.Ray{Origin: vec3.New(7.5, 1.5, 2.5), Dir: vec3.New(-1, 0, 0)}
+	hit, exists := world.Raycast(ray, 20)
+	if !exists {
+		t.Fatal("expected the ray to hit the voxel")
+	}
+	if hit.Position != (WorldPos{-6, 1, 2}) || hit.Previous != (WorldPos{-5, 1, 2}) {
+		t.Errorf("expected a hit at -6,1,2 from -5,1,2, got %+v", hit)
+	}
+	if hit.Normal != vec3.New(1, 0, 0) || hit.Distance != 12.5 {
+		t.Errorf("expected to hit the +x face at distance 12.5, got %+v", hit)
+	}
+}
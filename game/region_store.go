@@ -0,0 +1,303 @@
+package game
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RegionMagic identifies region files
+var RegionMagic = [4]byte{'G', 'W', 'R', 'G'}
+
+// RegionVersion is the current region file format version
+const RegionVersion = 1
+
+// ErrInvalidRegion is returned when a region file header can not be parsed
+var ErrInvalidRegion = errors.New("invalid region file")
+
+// ErrRegionSize is returned when a region file holds a different number of chunks than the store.
+// The region is valid, so it is left untouched.
+var ErrRegionSize = errors.New("region size mismatch")
+
+// regionHeader is stored at the beginning of every region file.
+// It is followed by Size*Size regionEntry structs, and then the chunk data.
+type regionHeader struct {
+	Magic   [4]byte
+	Version uint32
+	Size    uint32
+}
+
+// regionEntry points to a compressed chunk within a region file.
+// An entry with zero length is an empty slot.
+type regionEntry struct {
+	Offset uint32
+	Length uint32
+}
+
+// RegionStore packs Size x Size columns of chunks into a single region file.
+// Each chunk is compressed individually, and every write replaces the region file
+// atomically so that a failed write never leaves a truncated file behind.
+// Use SaveAll to save several chunks, since each write rewrites the entire region.
+type RegionStore struct {
+	Path string
+	Size int
+
+	lock sync.Mutex
+}
+
+// NewRegionStore creates a region file chunk store in the given directory
+func NewRegionStore(path string, size int) *RegionStore {
+	return &RegionStore{
+		Path: path,
+		Size: size,
+	}
+}
+
+// region returns the file name of the region containing the given chunk,
+// as well as the index of the chunk within that region.
+func (rs *RegionStore) region(cx, cy, cz int) (string, int) {
+	rx, rz := floorDiv(cx, rs.Size), floorDiv(cz, rs.Size)
+	lx, lz := floorMod(cx, rs.Size), floorMod(cz, rs.Size)
+	name := filepath.Join(rs.Path, fmt.Sprintf("r_%d_%d_%d.bin", rx, cy, rz))
+	return name, lz*rs.Size + lx
+}
+
+// Load a chunk from its region file
+func (rs *RegionStore) Load(cx, cy, cz int) (*Chunk, error) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	name, index := rs.region(cx, cy, cz)
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := rs.readTable(file)
+	if err != nil {
+		if !corruptRegion(err) {
+			return nil, fmt.Errorf("error reading region %s: %w", name, err)
+		}
+		// the region itself is damaged. move it out of the way so that it can be recreated
+		file.Close()
+		err = fmt.Errorf("%w: error reading region %s: %s", ErrCorruptChunk, name, err)
+		if qerr := quarantineFile(rs.Path, name); qerr != nil {
+			return nil, fmt.Errorf("%w (quarantine failed: %s)", err, qerr)
+		}
+		return nil, err
+	}
+
+	entry := entries[index]
+	if entry.Length == 0 {
+		return nil, &os.PathError{Op: "load", Path: name, Err: os.ErrNotExist}
+	}
+
+	data := make([]byte, entry.Length)
+	if _, err := file.ReadAt(data, int64(entry.Offset)); err != nil {
+		return nil, fmt.Errorf("error reading chunk %d,%d,%d: %w", cx, cy, cz, err)
+	}
+
+	chunk, err := decompressChunk(data)
+	if errors.Is(err, ErrUnsupportedVersion) {
+		return nil, fmt.Errorf("error reading chunk %d,%d,%d: %w", cx, cy, cz, err)
+	}
+	if err != nil {
+		if !errors.Is(err, ErrCorruptChunk) {
+			err = fmt.Errorf("%w: %s", ErrCorruptChunk, err)
+		}
+		chunkName := fmt.Sprintf("c_%d_%d_%d.bin", cx, cy, cz)
+		if qerr := quarantineData(rs.Path, chunkName, data); qerr != nil {
+			return nil, fmt.Errorf("%w (quarantine failed: %s)", err, qerr)
+		}
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// decompressChunk decodes a compressed chunk
+func decompressChunk(data []byte) (*Chunk, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return decodeChunk(reader)
+}
+
+// Save a chunk to its region file. The region file is rewritten to a temporary
+// file which then replaces the old region.
+func (rs *RegionStore) Save(chunk *Chunk) error {
+	return rs.SaveAll([]*Chunk{chunk})[0]
+}
+
+// SaveAll saves several chunks, rewriting each affected region file once.
+// If a region fails to save, every chunk in it fails.
+func (rs *RegionStore) SaveAll(chunks []*Chunk) []error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	errs := make([]error, len(chunks))
+	regions := map[string][]int{}
+	names := []string{}
+	for i, chunk := range chunks {
+		name, _ := rs.region(chunk.Cx, chunk.Cy, chunk.Cz)
+		if _, exists := regions[name]; !exists {
+			names = append(names, name)
+		}
+		regions[name] = append(regions[name], i)
+	}
+
+	for _, name := range names {
+		members := regions[name]
+		err := rs.saveRegion(name, chunks, members)
+		for _, i := range members {
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+// saveRegion writes the chunks at the given indices into a single region file
+func (rs *RegionStore) saveRegion(name string, chunks []*Chunk, indices []int) error {
+	region, err := rs.readRegion(name)
+	if err != nil {
+		return fmt.Errorf("error reading region %s: %w", name, err)
+	}
+	for _, i := range indices {
+		chunk := chunks[i]
+		var buffer bytes.Buffer
+		writer := zlib.NewWriter(&buffer)
+		if err := encodeChunk(writer, chunk); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		_, index := rs.region(chunk.Cx, chunk.Cy, chunk.Cz)
+		region[index] = buffer.Bytes()
+	}
+	return rs.writeRegion(name, region)
+}
+
+// readTable reads the header and offset table of a region file
+func (rs *RegionStore) readTable(r io.Reader) ([]regionEntry, error) {
+	header := regionHeader{}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Magic != RegionMagic || header.Version == 0 {
+		return nil, ErrInvalidRegion
+	}
+	if header.Version > RegionVersion {
+		return nil, fmt.Errorf("%w: region format version %d", ErrUnsupportedVersion, header.Version)
+	}
+	if int(header.Size) != rs.Size {
+		return nil, fmt.Errorf("%w: region holds %d by %d chunks, expected %d", ErrRegionSize, header.Size, header.Size, rs.Size)
+	}
+
+	entries := make([]regionEntry, rs.Size*rs.Size)
+	if err := binary.Read(r, binary.LittleEndian, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// corruptRegion returns true if an error returned by readTable is caused by damaged data,
+// rather than by a region this store can not read or a failure to read the file
+func corruptRegion(err error) bool {
+	return errors.Is(err, ErrInvalidRegion) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// readRegion reads all compressed chunks in a region file.
+// If the region does not exist, an empty region is returned.
+func (rs *RegionStore) readRegion(name string) ([][]byte, error) {
+	chunks := make([][]byte, rs.Size*rs.Size)
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return chunks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := rs.readTable(file)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if entry.Length == 0 {
+			continue
+		}
+		chunks[i] = make([]byte, entry.Length)
+		if _, err := file.ReadAt(chunks[i], int64(entry.Offset)); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// writeRegion atomically replaces a region file with the given compressed chunks
+func (rs *RegionStore) writeRegion(name string, chunks [][]byte) error {
+	if err := os.MkdirAll(rs.Path, 0755); err != nil {
+		return err
+	}
+
+	header := regionHeader{
+		Magic:   RegionMagic,
+		Version: RegionVersion,
+		Size:    uint32(rs.Size),
+	}
+	entries := make([]regionEntry, len(chunks))
+	offset := binary.Size(header) + binary.Size(entries)
+	for i, data := range chunks {
+		if len(data) == 0 {
+			continue
+		}
+		entries[i] = regionEntry{
+			Offset: uint32(offset),
+			Length: uint32(len(data)),
+		}
+		offset += len(data)
+	}
+
+	file, err := ioutil.TempFile(rs.Path, "region-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := rs.writeTo(file, header, entries, chunks); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), name)
+}
+
+func (rs *RegionStore) writeTo(file *os.File, header regionHeader, entries []regionEntry, chunks [][]byte) error {
+	if err := binary.Write(file, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if err := binary.Write(file, binary.LittleEndian, entries); err != nil {
+		return err
+	}
+	for _, data := range chunks {
+		if _, err := file.Write(data); err != nil {
+			return err
+		}
+	}
+	return file.Sync()
+}
@@ -0,0 +1,252 @@
+package game
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegionStoreRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewRegionStore(dir, 4)
+	chunks := []*Chunk{
+		NewChunk(4, 1, 0, 0, 0),
+		NewChunk(4, 1, 3, 0, 1),
+		NewChunk(4, 1, -1, 0, -1),
+		NewChunk(4, 1, 0, 2, 0),
+	}
+	for i, chunk := range chunks {
+		chunk.Set(1, 2, 3, Voxel{Block: ColorBlock, R: byte(i + 1), G: 2, B: 3})
+		if err := store.Save(chunk); err != nil {
+			t.Fatalf("error saving chunk %d: %s", i, err)
+		}
+	}
+
+	// overwrite a chunk in an existing region
+	chunks[0].Set(0, 0, 0, Voxel{Block: ColorBlock, R: 9})
+	if err := store.Save(chunks[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range chunks {
+		chunk, err := store.Load(expected.Cx, expected.Cy, expected.Cz)
+		if err != nil {
+			t.Fatalf("error loading chunk %d: %s", i, err)
+		}
+		if chunk.Cx != expected.Cx || chunk.Cy != expected.Cy || chunk.Cz != expected.Cz {
+			t.Errorf("loaded chunk %d has wrong coordinates", i)
+		}
+		if v := chunk.At(1, 2, 3); v != expected.At(1, 2, 3) {
+			t.Errorf("chunk %d has wrong voxel data: %v", i, v)
+		}
+	}
+
+	if chunk, _ := store.Load(0, 0, 0); chunk.At(0, 0, 0) != (Voxel{Block: ColorBlock, R: 9}) {
+		t.Error("expected overwritten chunk to be updated")
+	}
+
+	if _, err := store.Load(1, 0, 0); !os.IsNotExist(err) {
+		t.Errorf("expected missing chunk in existing region to not exist, got %v", err)
+	}
+	if _, err := store.Load(100, 0, 100); !os.IsNotExist(err) {
+		t.Errorf("expected missing region to not exist, got %v", err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 3 {
+		t.Errorf("expected 3 region files, found %d", len(files))
+	}
+}
+
+func TestConvertChunkDir(t *testing.T) {
+	src, err := ioutil.TempDir("", "chunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	files := NewFileStore(src)
+	for x := 0; x < 3; x++ {
+		chunk := NewChunk(4, 1, x, 0, -x)
+		chunk.Set(0, 1, 0, Voxel{Block: ColorBlock, G: byte(x + 1)})
+		if err := files.Save(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regions := NewRegionStore(dst, 2)
+	converted, err := ConvertChunkDir(src, regions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted != 3 {
+		t.Errorf("expected 3 converted chunks, was %d", converted)
+	}
+
+	for x := 0; x < 3; x++ {
+		chunk, err := regions.Load(x, 0, -x)
+		if err != nil {
+			t.Fatalf("error loading converted chunk: %s", err)
+		}
+		if v := chunk.At(0, 1, 0); v.G != byte(x+1) {
+			t.Errorf("converted chunk %d has wrong data", x)
+		}
+	}
+}
+
+// assertRegionUntouched fails if a region file changed or anything was quarantined
+func assertRegionUntouched(t *testing.T, dir, name string, expected []byte) {
+	t.Helper()
+	if data, err := ioutil.ReadFile(name); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("expected region file to be left untouched, err %v", err)
+	}
+	if quarantined, _ := filepath.Glob(filepath.Join(dir, QuarantineDir, "*")); len(quarantined) != 0 {
+		t.Errorf("expected nothing in quarantine, found %v", quarantined)
+	}
+}
+
+func TestRegionStoreNewerChunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// store a chunk written by a newer version of the chunk format
+	encoded := &bytes.Buffer{}
+	if err := encodeChunk(encoded, NewChunk(4, 1, 0, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	data := encoded.Bytes()
+	binary.LittleEndian.PutUint16(data[len(ChunkMagic):], ChunkFormatVersion+1)
+	compressed := &bytes.Buffer{}
+	writer := zlib.NewWriter(compressed)
+	writer.Write(data)
+	writer.Close()
+
+	store := NewRegionStore(dir, 2)
+	name, index := store.region(0, 0, 0)
+	chunks := make([][]byte, 4)
+	chunks[index] = compressed.Bytes()
+	if err := store.writeRegion(name, chunks); err != nil {
+		t.Fatal(err)
+	}
+	region, _ := ioutil.ReadFile(name)
+
+	if _, err := store.Load(0, 0, 0); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected unsupported version error, got %v", err)
+	}
+	assertRegionUntouched(t, dir, name, region)
+
+	// the world leaves the chunk out rather than generating a replacement
+	world := testWorld(store)
+	if _, err := world.AddChunk(0, 0, 0); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected the world to refuse loading the chunk, got %v", err)
+	}
+	if world.Chunk(ChunkPos{}) != nil {
+		t.Error("expected no chunk to be added to the world")
+	}
+	if err := world.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	assertRegionUntouched(t, dir, name, region)
+}
+
+func TestRegionStoreNewerRegion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewRegionStore(dir, 2)
+	if err := store.Save(NewChunk(4, 1, 0, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	name, _ := store.region(0, 0, 0)
+	region, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a region with a different size is not corrupt
+	if _, err := NewRegionStore(dir, 4).Load(0, 0, 0); !errors.Is(err, ErrRegionSize) {
+		t.Errorf("expected region size error, got %v", err)
+	}
+	assertRegionUntouched(t, dir, name, region)
+
+	// neither is a region written by a newer version
+	binary.LittleEndian.PutUint32(region[len(RegionMagic):], RegionVersion+1)
+	if err := ioutil.WriteFile(name, region, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(0, 0, 0); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected unsupported version error, got %v", err)
+	}
+	if err := store.Save(NewChunk(4, 1, 1, 0, 0)); err == nil {
+		t.Error("expected saving into a newer region to fail")
+	}
+	assertRegionUntouched(t, dir, name, region)
+}
+
+func TestRegionStoreSaveAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "regions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the second region is written by a newer version, so saving into it fails
+	store := NewRegionStore(dir, 2)
+	if err := store.Save(NewChunk(4, 1, 2, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	newer, _ := store.region(2, 0, 0)
+	region, _ := ioutil.ReadFile(newer)
+	binary.LittleEndian.PutUint32(region[len(RegionMagic):], RegionVersion+1)
+	if err := ioutil.WriteFile(newer, region, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := []*Chunk{
+		NewChunk(4, 1, 0, 0, 0),
+		NewChunk(4, 1, 2, 0, 1),
+		NewChunk(4, 1, 1, 0, 1),
+		NewChunk(4, 1, 1, 0, 0),
+	}
+	for i, chunk := range chunks {
+		chunk.Set(1, 1, 1, Voxel{Block: ColorBlock, R: byte(i + 1)})
+	}
+	errs := store.SaveAll(chunks)
+	for i, chunk := range chunks {
+		failed := chunk.Cx == 2
+		if (errs[i] != nil) != failed {
+			t.Errorf("chunk %d: expected failure=%t, got %v", i, failed, errs[i])
+		}
+		if failed {
+			continue
+		}
+		loaded, err := store.Load(chunk.Cx, chunk.Cy, chunk.Cz)
+		if err != nil {
+			t.Fatalf("error loading chunk %d: %s", i, err)
+		}
+		if v := loaded.At(1, 1, 1); v != chunk.At(1, 1, 1) {
+			t.Errorf("chunk %d has wrong voxel data: %v", i, v)
+		}
+	}
+}
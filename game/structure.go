@@ -0,0 +1,142 @@
+package game
+
+// Structure is a multi-voxel feature placed by the world generator, such as a tree or a ruin.
+// Structures may extend into neighboring chunks.
+type Structure interface {
+	// Extent returns the largest horizontal distance from the anchor covered by the structure
+	Extent() int
+
+	// Build places the voxels of the structure around an anchor voxel resting on the surface.
+	// All randomness must be drawn from rnd, so that the structure is identical every time it is built.
+	Build(anchor WorldPos, rnd *StructureRand, set func(WorldPos, Voxel))
+}
+
+// StructurePlacement is a structure that may appear in a biome
+type StructurePlacement struct {
+	Structure Structure
+
+	// Chance is the probability of the structure appearing in a structure cell
+	Chance float32
+}
+
+// StructureRand is a small deterministic random number generator
+type StructureRand uint32
+
+// Next returns the next random number
+func (r *StructureRand) Next() uint32 {
+	x := uint32(*r)
+	if x == 0 {
+		x = 0x9e3779b9
+	}
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	*r = StructureRand(x)
+	return x
+}
+
+// Range returns a random number in the range [min, max]
+func (r *StructureRand) Range(min, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + int(r.Next()%uint32(max-min+1))
+}
+
+// Float returns a random number in the range [0, 1)
+func (r *StructureRand) Float() float32 {
+	return hashFloat(r.Next())
+}
+
+// Tree is a trunk topped by a rounded crown of leaves
+type Tree struct {
+	Trunk     Voxel
+	Leaves    Voxel
+	MinHeight int
+	MaxHeight int
+	Crown     int
+}
+
+// Extent returns the radius of the crown
+func (t *Tree) Extent() int {
+	return t.Crown
+}
+
+// Build grows the tree upwards from the anchor
+func (t *Tree) Build(anchor WorldPos, rnd *StructureRand, set func(WorldPos, Voxel)) {
+	height := rnd.Range(t.MinHeight, t.MaxHeight)
+	top := anchor.Y + height
+	r := t.Crown
+	for dy := -r; dy <= r/2; dy++ {
+		for dz := -r; dz <= r; dz++ {
+			for dx := -r; dx <= r; dx++ {
+				d := dx*dx + dy*dy + dz*dz
+				if d > r*r || (d == r*r && rnd.Float() < 0.5) {
+					continue
+				}
+				set(WorldPos{anchor.X + dx, top + dy, anchor.Z + dz}, t.Leaves)
+			}
+		}
+	}
+	for y := anchor.Y; y < top; y++ {
+		set(WorldPos{anchor.X, y, anchor.Z}, t.Trunk)
+	}
+}
+
+// Boulder is a lump of rock, partially sunk into the ground
+type Boulder struct {
+	Rock      Voxel
+	MinRadius int
+	MaxRadius int
+}
+
+// Extent returns the largest radius of the boulder
+func (b *Boulder) Extent() int {
+	return b.MaxRadius
+}
+
+// Build places the boulder centered on the anchor
+func (b *Boulder) Build(anchor WorldPos, rnd *StructureRand, set func(WorldPos, Voxel)) {
+	r := rnd.Range(b.MinRadius, b.MaxRadius)
+	// squash the boulder by a random amount vertically
+	ry := r - rnd.Range(0, r/2)
+	for dy := -ry; dy <= ry; dy++ {
+		for dz := -r; dz <= r; dz++ {
+			for dx := -r; dx <= r; dx++ {
+				if dx*dx*ry*ry+dz*dz*ry*ry+dy*dy*r*r > r*r*ry*ry {
+					continue
+				}
+				set(WorldPos{anchor.X + dx, anchor.Y + dy, anchor.Z + dz}, b.Rock)
+			}
+		}
+	}
+}
+
+// Ruin is a square of crumbling walls
+type Ruin struct {
+	Wall   Voxel
+	Size   int
+	Height int
+}
+
+// Extent returns half the size of the ruin
+func (r *Ruin) Extent() int {
+	return r.Size / 2
+}
+
+// Build places the walls of the ruin around the anchor. Each section of the walls
+// crumbles to a random height, leaving gaps.
+func (r *Ruin) Build(anchor WorldPos, rnd *StructureRand, set func(WorldPos, Voxel)) {
+	h := r.Size / 2
+	for dz := -h; dz <= h; dz++ {
+		for dx := -h; dx <= h; dx++ {
+			if dx != -h && dx != h && dz != -h && dz != h {
+				continue
+			}
+			height := rnd.Range(0, r.Height)
+			for y := -1; y < height; y++ {
+				set(WorldPos{anchor.X + dx, anchor.Y + y, anchor.Z + dz}, r.Wall)
+			}
+		}
+	}
+}
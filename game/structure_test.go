@@ -0,0 +1,135 @@
+package game
+
+import (
+	"testing"
+)
+
+// generateChunks generates a set of chunks in the given order
+func generateChunks(wg *WorldGenerator, order []ChunkPos) map[ChunkPos]*Chunk {
+	chunks := make(map[ChunkPos]*Chunk, len(order))
+	for _, cp := range order {
+		chunks[cp] = wg.Chunk(cp.X, cp.Y, cp.Z)
+	}
+	return chunks
+}
+
+func TestStructuresSpanChunkBorders(t *testing.T) {
+	size := 16
+	wg := testWorldgen(t, 31481234, size)
+
+	// find a structure crossing a chunk border along the x axis
+	var placement *StructurePlacement
+	var anchor WorldPos
+	var cell [2]int
+	for c := 0; placement == nil && c < 1000; c++ {
+		p, a, exists := wg.structureIn(c, 3)
+		if exists && floorDiv(a.X-p.Structure.Extent(), size) != floorDiv(a.X+p.Structure.Extent(), size) {
+			placement, anchor, cell = p, a, [2]int{c, 3}
+		}
+	}
+	if placement == nil {
+		t.Fatal("expected to find a structure crossing a chunk border")
+	}
+
+	expected := map[WorldPos]Voxel{}
+	rnd := StructureRand(columnHash(wg.Seed, cell[0], cell[1], structureSalt+2))
+	placement.Structure.Build(anchor, &rnd, func(p WorldPos, v Voxel) {
+		expected[p] = v
+	})
+
+	// generate every chunk touched by the structure, in two different orders
+	touched := map[ChunkPos]bool{}
+	for p := range expected {
+		touched[p.Chunk(size)] = true
+	}
+	order := make([]ChunkPos, 0, len(touched))
+	for cp := range touched {
+		order = append(order, cp)
+	}
+	reversed := make([]ChunkPos, len(order))
+	for i, cp := range order {
+		reversed[len(order)-1-i] = cp
+	}
+	forward := generateChunks(wg, order)
+	backward := generateChunks(testWorldgen(t, 31481234, size), reversed)
+
+	matches := map[ChunkPos]int{}
+	for p, v := range expected {
+		cp, lp := p.Split(size)
+		a := forward[cp].At(lp.X, lp.Y, lp.Z)
+		if b := backward[cp].At(lp.X, lp.Y, lp.Z); a != b {
+			t.Fatalf("expected identical chunks regardless of generation order, %+v != %+v at %v", a, b, p)
+		}
+		if a != wg.Voxel(p.X, p.Y, p.Z) {
+			t.Fatalf("expected chunk voxel at %v to match the generator", p)
+		}
+		if a == v {
+			matches[cp]++
+		}
+	}
+	if len(matches) < 2 {
+		t.Errorf("expected the structure to appear in several chunks, found it in %d", len(matches))
+	}
+}
+
+func TestStructureExtent(t *testing.T) {
+	structures := []Structure{
+		&Tree{MinHeight: 3, MaxHeight: 7, Crown: 3},
+		&Boulder{MinRadius: 1, MaxRadius: 4},
+		&Ruin{Size: 7, Height: 3},
+	}
+	for _, structure := range structures {
+		for seed := 1; seed < 50; seed++ {
+			rnd := StructureRand(seed)
+			e := structure.Extent()
+			structure.Build(WorldPos{}, &rnd, func(p WorldPos, v Voxel) {
+				if p.X < -e || p.X > e || p.Z < -e || p.Z > e {
+					t.Fatalf("%T placed a voxel at %v, outside of its extent %d", structure, p, e)
+				}
+			})
+		}
+	}
+}
+
+// countingStructure counts how many times a structure is built
+type countingStructure struct {
+	Structure
+	builds *int
+}
+
+func (s countingStructure) Build(anchor WorldPos, rnd *StructureRand, set func(WorldPos, Voxel)) {
+	*s.builds++
+	s.Structure.Build(anchor, rnd, set)
+}
+
+func TestStructureCellsAreCached(t *testing.T) {
+	wg := testWorldgen(t, 31481234, 16)
+	builds := 0
+	for _, biome := range wg.Biomes {
+		for i := range biome.Structures {
+			biome.Structures[i].Structure = countingStructure{biome.Structures[i].Structure, &builds}
+		}
+	}
+
+	// querying every voxel of a column builds each nearby structure once
+	for y := 0; y < 64; y++ {
+		wg.Voxel(5, y, 5)
+	}
+	first := builds
+	extent := wg.structureExtent()
+	cells := (2*extent/StructureCellSize + 2) * (2*extent/StructureCellSize + 2)
+	if first > cells {
+		t.Errorf("expected at most %d structure builds, got %d", cells, first)
+	}
+
+	// chunks reuse the cells built for the column
+	wg.Chunk(0, 0, 0)
+	wg.Chunk(0, 0, 0)
+	for y := 0; y < 64; y++ {
+		wg.Voxel(5, y, 5)
+	}
+	chunkCells := (16+2*extent)/StructureCellSize + 2
+	if builds > chunkCells*chunkCells {
+		t.Errorf("expected at most %d structure builds, got %d", chunkCells*chunkCells, builds)
+	}
+}
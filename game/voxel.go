@@ -4,22 +4,73 @@ import (
 	"github.com/johanhenriksson/goworld/render"
 )
 
-// EmptyVoxel is an empty color voxel
+// MaxLightLevel is the highest block light level a voxel can emit
+const MaxLightLevel = 15
+
+// EmptyVoxel is an empty air voxel
 var EmptyVoxel = Voxel{}
 
 // Voxels is a collection of voxels
 type Voxels []Voxel
 
-// Voxel holds color information for a single colored voxel
+// Voxel is a single block, with a color tint
 type Voxel struct {
+	Block   BlockID
 	R, G, B byte
+
+	// Level is the distance of a flowing liquid from the liquid feeding it.
+	// Liquid sources and all other blocks have level 0.
+	Level byte
 }
 
-// NewVoxel creates a new Color Voxel from a given color
+// NewVoxel creates a new color block voxel with the given color
 func NewVoxel(color render.Color) Voxel {
+	return NewBlockVoxel(ColorBlock, color)
+}
+
+// NewBlockVoxel creates a voxel of the given block type, tinted by the given color
+func NewBlockVoxel(block BlockID, color render.Color) Voxel {
 	return Voxel{
-		R: byte(255 * color.R),
-		G: byte(255 * color.G),
-		B: byte(255 * color.B),
+		Block: block,
+		R:     byte(255 * color.R),
+		G:     byte(255 * color.G),
+		B:     byte(255 * color.B),
+	}
+}
+
+// Type returns the block type of the voxel
+func (v Voxel) Type() *BlockType {
+	return Block(v.Block)
+}
+
+// Source returns true if the voxel is a liquid source, which never drains
+func (v Voxel) Source() bool {
+	return v.Type().Liquid && v.Level == 0
+}
+
+// Emission returns the block light level emitted by the voxel in each color channel.
+// The brightest channel of the tint reaches the full radius of the block type,
+// the others are scaled down accordingly.
+func (v Voxel) Emission() [3]byte {
+	radius := int(v.Type().Light)
+	if radius == 0 {
+		return [3]byte{}
+	}
+	if radius > MaxLightLevel {
+		radius = MaxLightLevel
+	}
+	max := v.R
+	if v.G > max {
+		max = v.G
+	}
+	if v.B > max {
+		max = v.B
+	}
+	if max == 0 {
+		return [3]byte{}
+	}
+	scale := func(c byte) byte {
+		return byte((radius*int(c) + int(max)/2) / int(max))
 	}
+	return [3]byte{scale(v.R), scale(v.G), scale(v.B)}
 }
@@ -1,13 +1,19 @@
 package game
 
-// VoxelVertex represents a single RGB-colored voxel
+// VoxelVertex represents a single RGBA-colored voxel
 type VoxelVertex struct {
 	X byte `vtx:"position,uint8,3"`
 	Y byte `vtx:"skip"`
 	Z byte `vtx:"skip"`
 	N byte `vtx:"normal_id,uint8,1"`
-	R byte `vtx:"color,uint8,3,normalize"`
+	R byte `vtx:"color,uint8,4,normalize"`
 	G byte `vtx:"skip"`
 	B byte `vtx:"skip"`
+	A byte `vtx:"skip"`
 	O byte `vtx:"occlusion,uint8,1,normalize"`
+
+	// LR, LG, LB is the block light color at the vertex
+	LR byte `vtx:"light,uint8,3,normalize"`
+	LG byte `vtx:"skip"`
+	LB byte `vtx:"skip"`
 }
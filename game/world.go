@@ -1,17 +1,29 @@
 package game
 
 import (
+	"errors"
 	"fmt"
+	"os"
+
 	"github.com/johanhenriksson/goworld/math/vec3"
 )
 
+// heightSearchDepth limits how far below a point HeightAt will look for solid ground
+const heightSearchDepth = 256
+
 type ChunkProvider interface {
-	Chunk(x, z int) *Chunk
+	Chunk(x, y, z int) *Chunk
 	Voxel(x, y, z int) Voxel
 }
 
+// BiomeProvider is implemented by chunk providers that generate biomes
+type BiomeProvider interface {
+	BiomeAt(x, z int) *Biome
+}
+
 type ChunkPos struct {
 	X int
+	Y int
 	Z int
 }
 
@@ -22,53 +34,172 @@ type World struct {
 	DrawDistance int
 	Cache        map[ChunkPos]*Chunk
 	Provider     ChunkProvider
+	Store        ChunkStore
+
+	// SaveDelay is the number of seconds without edits before dirty chunks are saved
+	SaveDelay float32
+
+	// MaxSaveDelay is the maximum number of seconds a chunk may stay dirty during continuous editing
+	MaxSaveDelay float32
+
+	dirty     map[ChunkPos]*Chunk
+	quietTime float32
+	dirtyTime float32
+	listeners []ChunkListener
+	fluids    fluidState
 }
 
-func NewWorld(seed, size int) *World {
+// NewWorld creates a world generated by the named worldgen preset
+func NewWorld(preset string, seed, size int) (*World, error) {
+	wgp, err := LoadWorldgenPreset(preset)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := wgp.Generator(seed, size)
+	if err != nil {
+		return nil, err
+	}
+	return newWorld(seed, size, provider), nil
+}
+
+// newWorld creates a world with the given chunk provider
+func newWorld(seed, size int, provider ChunkProvider) *World {
 	return &World{
 		Seed:         seed,
 		KeepDistance: 5,
 		DrawDistance: 3,
 		ChunkSize:    size,
 		Cache:        make(map[ChunkPos]*Chunk),
-		Provider:     ExampleWorldgen(seed, size),
+		Provider:     provider,
+		Store:        NewRegionStore("regions", 16),
+		SaveDelay:    2,
+		MaxSaveDelay: 10,
+		dirty:        make(map[ChunkPos]*Chunk),
 	}
 }
 
-func (w *World) AddChunk(cx, cz int) *Chunk {
-	chunk, err := LoadChunk("chunks", cx, cz)
+// AddChunk loads or generates a chunk and adds it to the chunk cache.
+// Returns an error if a stored chunk could not be loaded, see FetchChunk.
+func (w *World) AddChunk(cx, cy, cz int) (*Chunk, error) {
+	chunk, err := w.FetchChunk(cx, cy, cz)
 	if err != nil {
-		chunk = w.Provider.Chunk(cx, cz)
-		fmt.Printf("Generated chunk %d,%d\n", cx, cz)
+		return nil, err
+	}
+	w.Insert(chunk)
+	return chunk, nil
+}
+
+// FetchChunk loads a chunk from the store, or generates it if it has never been stored or its data
+// was corrupt. If the chunk exists but can not be read, such as a chunk written by a newer version
+// or one that failed to read from disk, an error is returned instead, since saving a generated
+// chunk would overwrite it.
+// The chunk is not added to the cache, so it is safe to call from a background worker.
+func (w *World) FetchChunk(cx, cy, cz int) (*Chunk, error) {
+	chunk, err := w.Store.Load(cx, cy, cz)
+	if err == nil {
+		return chunk, nil
+	}
+	if errors.Is(err, ErrCorruptChunk) {
+		fmt.Printf("Quarantined corrupt chunk %d,%d,%d: %s\n", cx, cy, cz, err)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error loading chunk %d,%d,%d: %w", cx, cy, cz, err)
 	}
+	chunk = w.Provider.Chunk(cx, cy, cz)
+	fmt.Printf("Generated chunk %d,%d,%d\n", cx, cy, cz)
+	return chunk, nil
+}
+
+// Insert a chunk into the chunk cache
+func (w *World) Insert(chunk *Chunk) {
+	cp := ChunkPos{chunk.Cx, chunk.Cy, chunk.Cz}
+	w.Cache[cp] = chunk
+	w.Relight(cp)
+	w.scheduleChunkFluids(cp)
+	w.emit(ChunkEvent{
+		Type:    ChunkLoaded,
+		Chunk:   cp,
+		Touched: neighborsOf(cp, true),
+	})
+}
+
+// Chunk returns the cached chunk at the given chunk position, or nil if it is not loaded.
+func (w *World) Chunk(cp ChunkPos) *Chunk {
+	return w.Cache[cp]
+}
 
-	w.Cache[ChunkPos{cx, cz}] = chunk
-	return chunk
+// Unload removes a chunk from the chunk cache. If the chunk has unsaved changes, it is saved first.
+// Should saving fail, the chunk remains queued for saving and will be retried on the next flush.
+func (w *World) Unload(cp ChunkPos) error {
+	w.unlight(cp)
+	w.emit(ChunkEvent{
+		Type:    ChunkUnloaded,
+		Chunk:   cp,
+		Touched: neighborsOf(cp, false),
+	})
+	if chunk, dirty := w.dirty[cp]; dirty {
+		if err := w.Store.Save(chunk); err != nil {
+			return fmt.Errorf("error saving chunk %d,%d,%d: %w", cp.X, cp.Y, cp.Z, err)
+		}
+		delete(w.dirty, cp)
+	}
+	return nil
 }
 
+// Voxel returns the voxel at the given world position. If the chunk is not loaded,
+// the voxel is sampled from the chunk provider.
 func (w *World) Voxel(x, y, z int) Voxel {
-	cx, cz := x/w.ChunkSize, z/w.ChunkSize
-	lx, ly, lz := x%w.ChunkSize, y, z%w.ChunkSize
-	if chunk, exists := w.Cache[ChunkPos{cx, cz}]; exists {
-		return chunk.At(lx, ly, lz)
+	cp, lp := WorldPos{x, y, z}.Split(w.ChunkSize)
+	if chunk, exists := w.Cache[cp]; exists {
+		return chunk.At(lp.X, lp.Y, lp.Z)
 	}
 	return w.Provider.Voxel(x, y, z)
 }
 
+// Set the voxel at the given world position, update the light of the surrounding chunks and
+// let nearby liquids flow. Nothing happens if the chunk is not loaded.
 func (w *World) Set(x, y, z int, voxel Voxel) {
-	cx, cz := x/w.ChunkSize, z/w.ChunkSize
-	lx, ly, lz := x%w.ChunkSize, y, z%w.ChunkSize
-	if chunk, exists := w.Cache[ChunkPos{cx, cz}]; exists {
-		chunk.Set(lx, ly, lz, voxel)
-		chunk.Light.Block(lx, ly, lz, voxel != EmptyVoxel)
+	cp, lp := WorldPos{x, y, z}.Split(w.ChunkSize)
+	if chunk, exists := w.Cache[cp]; exists {
+		chunk.Set(lp.X, lp.Y, lp.Z, voxel)
+		w.MarkDirty(chunk)
+		w.updateLight(WorldPos{x, y, z})
+		w.scheduleFluid(WorldPos{x, y, z})
+		w.emit(ChunkEvent{
+			Type:    ChunkModified,
+			Chunk:   cp,
+			Touched: touchedBy(cp, lp, w.ChunkSize),
+		})
 	}
 }
 
+// HeightAt returns the height of the first solid voxel at or below the given point
 func (w *World) HeightAt(p vec3.T) float32 {
-	x, y, z := int(p.X), int(p.Y), int(p.Z)
-	for w.Voxel(x, y, z) == EmptyVoxel && y >= 0 {
+	wp := WorldPosAt(p)
+	x, y, z := wp.X, wp.Y, wp.Z
+	floor := y - heightSearchDepth
+	for !w.Voxel(x, y, z).Type().Solid && y >= floor {
 		y--
 	}
 	y++
 	return float32(y)
 }
+
+// Solid returns true if the voxel at the given world position is solid
+func (w *World) Solid(x, y, z int) bool {
+	return w.Voxel(x, y, z).Type().Solid
+}
+
+// BlockAt returns the block type of the voxel containing the given point
+func (w *World) BlockAt(p vec3.T) *BlockType {
+	wp := WorldPosAt(p)
+	return w.Voxel(wp.X, wp.Y, wp.Z).Type()
+}
+
+// BiomeAt returns the biome of the column at the given world position,
+// or nil if the chunk provider does not generate biomes
+func (w *World) BiomeAt(x, z int) *Biome {
+	if biomes, ok := w.Provider.(BiomeProvider); ok {
+		return biomes.BiomeAt(x, z)
+	}
+	return nil
+}
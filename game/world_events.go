@@ -0,0 +1,101 @@
+package game
+
+// ChunkEventType describes what happened to a chunk
+type ChunkEventType int
+
+const (
+	// ChunkLoaded is emitted when a chunk is inserted into the world cache
+	ChunkLoaded ChunkEventType = iota
+
+	// ChunkUnloaded is emitted when a chunk is removed from the world cache
+	ChunkUnloaded
+
+	// ChunkModified is emitted when the voxels of a loaded chunk change
+	ChunkModified
+
+	// ChunkRelit is emitted when the light volume of a loaded chunk has been recomputed
+	ChunkRelit
+)
+
+// ChunkEvent notifies listeners about changes to the chunks of a world
+type ChunkEvent struct {
+	Type  ChunkEventType
+	Chunk ChunkPos
+
+	// Touched lists every chunk whose meshing or lighting may be affected by the event.
+	// For a modified voxel this is the chunk itself, and any neighbors bordering the voxel.
+	Touched []ChunkPos
+}
+
+// ChunkListener receives chunk events
+type ChunkListener func(ChunkEvent)
+
+// Subscribe registers a listener for chunk events. Events are emitted on the thread
+// that modifies the world, which is usually the main thread.
+func (w *World) Subscribe(listener ChunkListener) {
+	w.listeners = append(w.listeners, listener)
+}
+
+// Touch notifies listeners that an entire chunk has been modified, for example after
+// it has been cleared. The chunk is marked as dirty and relit.
+func (w *World) Touch(chunk *Chunk) {
+	cp := ChunkPos{chunk.Cx, chunk.Cy, chunk.Cz}
+	w.MarkDirty(chunk)
+	w.Relight(cp)
+	w.scheduleChunkFluids(cp)
+	w.emit(ChunkEvent{
+		Type:    ChunkModified,
+		Chunk:   cp,
+		Touched: neighborsOf(cp, true),
+	})
+}
+
+func (w *World) emit(event ChunkEvent) {
+	for _, listener := range w.listeners {
+		listener(event)
+	}
+}
+
+// neighborsOf returns the positions of the 26 chunks surrounding a chunk,
+// optionally including the chunk itself.
+func neighborsOf(cp ChunkPos, self bool) []ChunkPos {
+	positions := make([]ChunkPos, 0, 27)
+	for dz := -1; dz <= 1; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 && dz == 0 && !self {
+					continue
+				}
+				positions = append(positions, ChunkPos{cp.X + dx, cp.Y + dy, cp.Z + dz})
+			}
+		}
+	}
+	return positions
+}
+
+// touchedBy returns the chunk containing a modified voxel, and all neighboring
+// chunks that share a face, edge or corner with the voxel.
+func touchedBy(cp ChunkPos, lp LocalPos, size int) []ChunkPos {
+	offsets := func(v int) []int {
+		switch {
+		case size == 1:
+			return []int{-1, 0, 1}
+		case v == 0:
+			return []int{-1, 0}
+		case v == size-1:
+			return []int{0, 1}
+		default:
+			return []int{0}
+		}
+	}
+
+	positions := make([]ChunkPos, 0, 8)
+	for _, dz := range offsets(lp.Z) {
+		for _, dy := range offsets(lp.Y) {
+			for _, dx := range offsets(lp.X) {
+				positions = append(positions, ChunkPos{cp.X + dx, cp.Y + dy, cp.Z + dz})
+			}
+		}
+	}
+	return positions
+}
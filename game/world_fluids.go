@@ -0,0 +1,208 @@
+package game
+
+import (
+	"sort"
+)
+
+// FluidTickRate is the number of fluid simulation ticks per second
+const FluidTickRate = 20
+
+// MaxFluidCatchUp is the largest number of fluid ticks simulated by a single call to
+// SimulateFluids. Any time beyond it, such as after a long stall, is dropped.
+const MaxFluidCatchUp = FluidTickRate
+
+// tickEpsilon absorbs rounding errors in accumulated frame times of fixed rate simulations,
+// so that frame times adding up to a whole tick always produce that tick
+const tickEpsilon = 1e-3
+
+// horizontalDirections are the offsets to the four horizontal neighbors of a voxel
+var horizontalDirections = [4]WorldPos{
+	{-1, 0, 0}, {1, 0, 0},
+	{0, 0, -1}, {0, 0, 1},
+}
+
+// fluidState holds the progress of the fluid simulation of a world
+type fluidState struct {
+	// tick is the number of ticks simulated so far
+	tick uint64
+
+	// time is the number of seconds simulated so far
+	time float64
+
+	// pending maps voxels that may need to flow to the tick they were scheduled at
+	pending map[WorldPos]uint64
+}
+
+// fluidChange is a voxel update computed by a fluid tick
+type fluidChange struct {
+	Position WorldPos
+	Voxel    Voxel
+}
+
+// SimulateFluids advances the fluid simulation by dt seconds. The simulation runs at a fixed
+// FluidTickRate, so the outcome only depends on the total time simulated, not on how it is split
+// into frames. Ticks stepped manually with StepFluids are counted towards the simulated time.
+// At most MaxFluidCatchUp ticks are simulated per call, the rest of the backlog is dropped.
+func (w *World) SimulateFluids(dt float32) {
+	w.fluids.time += float64(dt)
+	due := uint64(w.fluids.time*FluidTickRate + tickEpsilon)
+	if due > w.fluids.tick+MaxFluidCatchUp {
+		due = w.fluids.tick + MaxFluidCatchUp
+		w.fluids.time = float64(due) / FluidTickRate
+	}
+	for w.fluids.tick < due {
+		w.StepFluids()
+	}
+}
+
+// StepFluids runs a single tick of the fluid simulation.
+//
+// Liquids are simulated as a cellular automaton. Each tick, the new state of every scheduled
+// voxel is computed from the current state of its neighbors, before any changes are applied,
+// so that the result does not depend on the order voxels are visited in:
+//
+//   - Air or flowing liquid below a liquid becomes falling liquid of level 1.
+//   - Air or flowing liquid next to a liquid that is resting on something, or next to a source,
+//     becomes flowing liquid with a level one higher than its neighbor, up to the Flow of the liquid.
+//   - Flowing liquid that is no longer fed by any neighbor drains, and becomes air.
+//
+// Changes take FlowDelay ticks of the liquid to happen. Every change schedules the neighbors
+// of the changed voxel, so liquids keep flowing until they settle.
+func (w *World) StepFluids() {
+	w.fluids.tick++
+	tick := w.fluids.tick
+
+	positions := make([]WorldPos, 0, len(w.fluids.pending))
+	for p := range w.fluids.pending {
+		positions = append(positions, p)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		a, b := positions[i], positions[j]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		if a.Z != b.Z {
+			return a.Z < b.Z
+		}
+		return a.X < b.X
+	})
+
+	changes := make([]fluidChange, 0, len(positions))
+	for _, p := range positions {
+		current := w.fluidAt(p)
+		next := w.flowInto(p, current)
+		if next == current {
+			delete(w.fluids.pending, p)
+			continue
+		}
+
+		liquid := next.Type()
+		if !liquid.Liquid {
+			liquid = current.Type()
+		}
+		if tick-w.fluids.pending[p] < uint64(liquid.FlowDelay) {
+			continue
+		}
+		delete(w.fluids.pending, p)
+		changes = append(changes, fluidChange{Position: p, Voxel: next})
+	}
+
+	for _, change := range changes {
+		w.Set(change.Position.X, change.Position.Y, change.Position.Z, change.Voxel)
+	}
+}
+
+// FluidsPending returns the number of voxels waiting to be updated by the fluid simulation.
+// Liquids have settled when there are none.
+func (w *World) FluidsPending() int {
+	return len(w.fluids.pending)
+}
+
+// scheduleFluid queues a voxel and its neighbors for the next fluid tick
+func (w *World) scheduleFluid(p WorldPos) {
+	if w.fluids.pending == nil {
+		w.fluids.pending = make(map[WorldPos]uint64)
+	}
+	schedule := func(p WorldPos) {
+		if _, exists := w.fluids.pending[p]; !exists {
+			w.fluids.pending[p] = w.fluids.tick
+		}
+	}
+	schedule(p)
+	for _, d := range lightDirections {
+		schedule(WorldPos{p.X + d[0], p.Y + d[1], p.Z + d[2]})
+	}
+}
+
+// scheduleChunkFluids queues the liquids of a chunk, and those bordering it, for the next fluid tick
+func (w *World) scheduleChunkFluids(cp ChunkPos) {
+	origin := cp.Origin(w.ChunkSize)
+	for z := -1; z <= w.ChunkSize; z++ {
+		for y := -1; y <= w.ChunkSize; y++ {
+			for x := -1; x <= w.ChunkSize; x++ {
+				p := WorldPos{origin.X + x, origin.Y + y, origin.Z + z}
+				if w.fluidAt(p).Type().Liquid {
+					w.scheduleFluid(p)
+				}
+			}
+		}
+	}
+}
+
+// fluidAt returns the voxel at the given world position. Voxels of chunks that are not loaded
+// are reported as bedrock, so that liquids never flow out of the loaded part of the world.
+func (w *World) fluidAt(p WorldPos) Voxel {
+	cp, lp := p.Split(w.ChunkSize)
+	chunk, exists := w.Cache[cp]
+	if !exists {
+		return Voxel{Block: BedrockBlock}
+	}
+	return chunk.At(lp.X, lp.Y, lp.Z)
+}
+
+// fallsInto returns true if a liquid would rather fall into the given voxel than spread sideways
+func fallsInto(liquid BlockID, below Voxel) bool {
+	return below.Block == AirBlock || (below.Block == liquid && !below.Source())
+}
+
+// flowInto returns the voxel that should replace the current voxel at the given position,
+// according to the liquids surrounding it
+func (w *World) flowInto(p WorldPos, current Voxel) Voxel {
+	flowing := current.Type().Liquid && !current.Source()
+	if current.Block != AirBlock && !flowing {
+		// solid blocks and liquid sources are never replaced
+		return current
+	}
+
+	next, fed := EmptyVoxel, false
+	feed := func(from Voxel, level int) {
+		liquid := from.Type()
+		if !liquid.Liquid || level > int(liquid.Flow) {
+			return
+		}
+		if flowing && from.Block != current.Block {
+			// liquids do not mix
+			return
+		}
+		if fed && (level > int(next.Level) || (level == int(next.Level) && from.Block >= next.Block)) {
+			return
+		}
+		next = Voxel{Block: from.Block, R: from.R, G: from.G, B: from.B, Level: byte(level)}
+		fed = true
+	}
+
+	feed(w.fluidAt(WorldPos{p.X, p.Y + 1, p.Z}), 1)
+	for _, d := range horizontalDirections {
+		n := WorldPos{p.X + d.X, p.Y, p.Z + d.Z}
+		neighbor := w.fluidAt(n)
+		if !neighbor.Type().Liquid {
+			continue
+		}
+		if !neighbor.Source() && fallsInto(neighbor.Block, w.fluidAt(WorldPos{n.X, n.Y - 1, n.Z})) {
+			// flowing liquid falls before it spreads
+			continue
+		}
+		feed(neighbor, int(neighbor.Level)+1)
+	}
+	return next
+}
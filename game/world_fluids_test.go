@@ -0,0 +1,145 @@
+package game
+
+import (
+	"testing"
+)
+
+// fluidWorld creates a world of 3x1x1 chunks with a stone floor at y = 0
+func fluidWorld() *World {
+	world := testWorld(newMemoryStore())
+	for cx := -1; cx <= 1; cx++ {
+		world.AddChunk(cx, 0, 0)
+	}
+	for x := -4; x < 8; x++ {
+		for z := 0; z < 4; z++ {
+			world.Set(x, 0, z, Voxel{Block: ColorBlock, R: 100})
+		}
+	}
+	return world
+}
+
+// settleFluids steps the fluid simulation until no voxels are pending
+func settleFluids(t *testing.T, world *World) {
+	t.Helper()
+	for i := 0; world.FluidsPending() > 0; i++ {
+		if i > 1000 {
+			t.Fatalf("expected fluids to settle, %d voxels pending", world.FluidsPending())
+		}
+		world.StepFluids()
+	}
+}
+
+func TestFluidSpreadAcrossChunks(t *testing.T) {
+	world := fluidWorld()
+	water := Voxel{Block: WaterBlock, B: 255}
+	world.Set(2, 1, 1, water)
+	settleFluids(t, world)
+
+	flow := int(Block(WaterBlock).Flow)
+	for x := -4; x < 8; x++ {
+		distance := x - 2
+		if distance < 0 {
+			distance = -distance
+		}
+		v := world.Voxel(x, 1, 1)
+		if distance > flow {
+			if v != EmptyVoxel {
+				t.Errorf("expected water to stop spreading before %d, found %+v", x, v)
+			}
+			continue
+		}
+		if v.Block != WaterBlock || int(v.Level) != distance || v.B != 255 {
+			t.Errorf("expected water of level %d at %d, found %+v", distance, x, v)
+		}
+	}
+
+	// removing the source drains the flowing water
+	world.Set(2, 1, 1, EmptyVoxel)
+	settleFluids(t, world)
+	for x := -4; x < 8; x++ {
+		for z := 0; z < 4; z++ {
+			if v := world.Voxel(x, 1, z); v != EmptyVoxel {
+				t.Fatalf("expected water to drain, found %+v at %d,1,%d", v, x, z)
+			}
+		}
+	}
+}
+
+func TestFluidFallsIntoPool(t *testing.T) {
+	world := fluidWorld()
+	stone := Voxel{Block: ColorBlock, R: 100}
+
+	// a 2x2 pit, surrounded by a wall one voxel high
+	for z := 0; z < 4; z++ {
+		world.Set(-1, 1, z, stone)
+		world.Set(2, 1, z, stone)
+	}
+	for x := 0; x <= 1; x++ {
+		world.Set(x, 1, 2, stone)
+		for z := 0; z <= 1; z++ {
+			world.Set(x, 0, z, EmptyVoxel)
+		}
+	}
+
+	lava := Voxel{Block: LavaBlock, R: 255, G: 100}
+	world.Set(0, 1, 0, lava)
+	settleFluids(t, world)
+
+	if v := world.Voxel(0, 0, 0); v.Block != LavaBlock || v.Level != 1 {
+		t.Errorf("expected falling lava below the source, found %+v", v)
+	}
+	for z := 0; z <= 1; z++ {
+		for x := 0; x <= 1; x++ {
+			if v := world.Voxel(x, 0, z); v.Block != LavaBlock {
+				t.Errorf("expected the pit to fill with lava at %d,0,%d, found %+v", x, z, v)
+			}
+		}
+	}
+	if v := world.Voxel(1, 1, 1); v != EmptyVoxel {
+		t.Errorf("expected lava to fall into the pit rather than spread above it, found %+v", v)
+	}
+	if v := world.Voxel(-2, 1, 0); v != EmptyVoxel {
+		t.Errorf("expected lava to stay within the walls, found %+v", v)
+	}
+}
+
+func TestFluidTickRateIndependence(t *testing.T) {
+	simulate := func(dt float32, frames int) *World {
+		world := fluidWorld()
+		world.Set(2, 3, 1, Voxel{Block: WaterBlock, B: 255})
+		for i := 0; i < frames; i++ {
+			world.SimulateFluids(dt)
+		}
+		return world
+	}
+
+	// two seconds of simulation, with very different frame rates
+	slow := simulate(0.25, 8)
+	fast := simulate(1.0/144, 288)
+	if slow.fluids.tick != 2*FluidTickRate || fast.fluids.tick != slow.fluids.tick {
+		t.Fatalf("expected %d ticks, got %d and %d", 2*FluidTickRate, slow.fluids.tick, fast.fluids.tick)
+	}
+	for cp, chunk := range slow.Cache {
+		other := fast.Cache[cp]
+		for i, v := range chunk.Data.Voxels() {
+			if other.Data.Get(i) != v {
+				t.Fatalf("chunk %v differs at voxel %d: %+v != %+v", cp, i, v, other.Data.Get(i))
+			}
+		}
+	}
+}
+
+func TestFluidCatchUpLimit(t *testing.T) {
+	world := fluidWorld()
+	world.Set(2, 3, 1, Voxel{Block: WaterBlock, B: 255})
+
+	// a long stall only simulates a limited number of ticks, and the rest is dropped
+	world.SimulateFluids(10)
+	if world.fluids.tick != MaxFluidCatchUp {
+		t.Fatalf("expected %d ticks after a stall, got %d", MaxFluidCatchUp, world.fluids.tick)
+	}
+	world.SimulateFluids(1.0 / FluidTickRate)
+	if world.fluids.tick != MaxFluidCatchUp+1 {
+		t.Errorf("expected the dropped backlog not to be simulated later, got %d ticks", world.fluids.tick)
+	}
+}
@@ -0,0 +1,147 @@
+package game
+
+// lightGrid returns a light grid spanning all loaded chunks, using world coordinates.
+// Only the voxels within each chunk are part of the grid. The extra top layer of each
+// light volume is kept in sync with the chunk above by syncSkyLayer.
+func (w *World) lightGrid() lightGrid {
+	return func(x, y, z int) *LightVoxel {
+		cp, lp := WorldPos{x, y, z}.Split(w.ChunkSize)
+		chunk, exists := w.Cache[cp]
+		if !exists {
+			return nil
+		}
+		return chunk.Light.Get(lp.X, lp.Y, lp.Z)
+	}
+}
+
+// lightPropagator returns a light propagator spanning all loaded chunks.
+// The positions of all chunks with modified light are recorded in relit.
+func (w *World) lightPropagator(relit map[ChunkPos]bool) *lightPropagator {
+	p := newLightPropagator(w.lightGrid(), DefaultLightFalloff)
+	p.changed = func(x, y, z int) {
+		relit[WorldPos{x, y, z}.Chunk(w.ChunkSize)] = true
+	}
+	return p
+}
+
+// updateLight propagates the effects of changing the blocked state or emission of a single voxel
+func (w *World) updateLight(wp WorldPos) {
+	relit := make(map[ChunkPos]bool)
+	p := w.lightPropagator(relit)
+	p.Darken(wp.X, wp.Y, wp.Z)
+	p.Run()
+	p.Refresh(wp.X, wp.Y, wp.Z)
+	p.Run()
+	w.relit(relit)
+}
+
+// Relight recomputes the light of the given loaded chunks. Light is removed from the chunks,
+// along with any light that spread from them into their neighbors, before being flood filled
+// back in from the sky and surrounding chunks. Chunks that are not loaded are treated as open
+// sky above, and darkness to the sides and below. Listeners receive a ChunkRelit event for
+// every chunk whose light changed.
+func (w *World) Relight(positions ...ChunkPos) {
+	relit := make(map[ChunkPos]bool)
+	p := w.lightPropagator(relit)
+
+	loaded := make([]*Chunk, 0, len(positions))
+	for _, cp := range positions {
+		if chunk, exists := w.Cache[cp]; exists {
+			loaded = append(loaded, chunk)
+		}
+	}
+
+	// remove existing light. the top row of the chunk below may have been lit by sky light
+	// that is now covered by the chunk
+	for _, chunk := range loaded {
+		w.forEachVoxel(chunk, 0, chunk.Sy, p.Darken)
+		if below, exists := w.Cache[ChunkPos{chunk.Cx, chunk.Cy - 1, chunk.Cz}]; exists {
+			w.forEachVoxel(below, below.Sy-1, below.Sy, p.Darken)
+		}
+	}
+	p.Run()
+
+	for _, chunk := range loaded {
+		w.forEachVoxel(chunk, 0, chunk.Sy, p.Refresh)
+		if below, exists := w.Cache[ChunkPos{chunk.Cx, chunk.Cy - 1, chunk.Cz}]; exists {
+			w.forEachVoxel(below, below.Sy-1, below.Sy, p.Refresh)
+		}
+		relit[ChunkPos{chunk.Cx, chunk.Cy, chunk.Cz}] = true
+	}
+	p.Run()
+	w.relit(relit)
+}
+
+// unlight removes the light of a chunk from its neighbors before it is unloaded from the cache.
+// Once the chunk is gone, the chunk below is exposed to the sky.
+func (w *World) unlight(cp ChunkPos) {
+	chunk, exists := w.Cache[cp]
+	if !exists {
+		return
+	}
+
+	relit := make(map[ChunkPos]bool)
+	p := w.lightPropagator(relit)
+	w.forEachVoxel(chunk, 0, chunk.Sy, p.Darken)
+	delete(w.Cache, cp)
+	p.Run()
+
+	if below, exists := w.Cache[ChunkPos{cp.X, cp.Y - 1, cp.Z}]; exists {
+		w.forEachVoxel(below, below.Sy-1, below.Sy, p.Refresh)
+	}
+	p.Run()
+
+	// the unloaded chunk itself no longer needs to be remeshed
+	delete(relit, cp)
+	w.relit(relit)
+}
+
+// forEachVoxel calls fn with the world coordinates of each voxel in the given layers of a chunk
+func (w *World) forEachVoxel(chunk *Chunk, y0, y1 int, fn func(x, y, z int)) {
+	for z := 0; z < chunk.Sz; z++ {
+		for x := 0; x < chunk.Sx; x++ {
+			for y := y0; y < y1; y++ {
+				fn(chunk.Ox+x, chunk.Oy+y, chunk.Oz+z)
+			}
+		}
+	}
+}
+
+// relit synchronizes the sky layers of chunks with modified light and notifies listeners
+func (w *World) relit(relit map[ChunkPos]bool) {
+	for cp := range relit {
+		w.syncSkyLayer(cp)
+		w.syncSkyLayer(ChunkPos{cp.X, cp.Y - 1, cp.Z})
+	}
+	for cp := range relit {
+		w.emit(ChunkEvent{
+			Type:    ChunkRelit,
+			Chunk:   cp,
+			Touched: neighborsOf(cp, true),
+		})
+	}
+}
+
+// syncSkyLayer copies the light of the bottom layer of the chunk above into the top layer of a
+// chunk's light volume. If there is no chunk above, the top layer receives full sky light.
+func (w *World) syncSkyLayer(cp ChunkPos) {
+	chunk, exists := w.Cache[cp]
+	if !exists {
+		return
+	}
+	above := w.Cache[ChunkPos{cp.X, cp.Y + 1, cp.Z}]
+	top := chunk.Light.Sy - 1
+	for z := 0; z < chunk.Sz; z++ {
+		for x := 0; x < chunk.Sx; x++ {
+			lv := chunk.Light.Get(x, top, z)
+			switch {
+			case lv.Blocked:
+				lv.V = 0
+			case above != nil:
+				lv.V = above.Light.Get(x, 0, z).V
+			default:
+				lv.V = 1
+			}
+		}
+	}
+}
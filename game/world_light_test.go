@@ -0,0 +1,122 @@
+package game
+
+import (
+	"math"
+	"testing"
+)
+
+func assertLight(t *testing.T, chunk *Chunk, x, y, z int, expected float32) {
+	t.Helper()
+	if v := chunk.Light.Get(x, y, z).V; math.Abs(float64(v-expected)) > 1e-5 {
+		t.Errorf("expected light %.4f at %d,%d,%d in chunk %d,%d,%d, got %.4f",
+			expected, x, y, z, chunk.Cx, chunk.Cy, chunk.Cz, v)
+	}
+}
+
+func TestLightAcrossChunkBorder(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	size := world.ChunkSize
+	a := solidChunk(size, 0, 0, 0)
+	b := solidChunk(size, 1, 0, 0)
+
+	// a tunnel along the x axis at y = 1, z = 1, spanning both chunks.
+	// a shaft in the first chunk lets sky light into the tunnel at x = 1
+	for x := 0; x < size; x++ {
+		a.Set(x, 1, 1, EmptyVoxel)
+		b.Set(x, 1, 1, EmptyVoxel)
+	}
+	for y := 2; y < size; y++ {
+		a.Set(1, y, 1, EmptyVoxel)
+	}
+	world.Insert(a)
+	world.Insert(b)
+
+	falloff := a.Light.Falloff
+	light := float32(1)
+	for x := 1; x < 2*size; x++ {
+		if x < size {
+			assertLight(t, a, x, 1, 1, light)
+		} else {
+			assertLight(t, b, x-size, 1, 1, light)
+		}
+		light *= falloff
+	}
+
+	// closing the shaft darkens the tunnel in both chunks
+	world.Set(1, size-1, 1, Voxel{Block: ColorBlock, R: 1, G: 1, B: 1})
+	assertLight(t, a, 1, 1, 1, 0)
+	assertLight(t, b, 0, 1, 1, 0)
+}
+
+func TestLightStackedChunks(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	size := world.ChunkSize
+	lower := solidChunk(size, 0, 0, 0)
+	for y := 0; y < size; y++ {
+		lower.Set(1, y, 1, EmptyVoxel)
+	}
+	world.Insert(lower)
+
+	// without a chunk above, the shaft is open to the sky
+	assertLight(t, lower, 1, 0, 1, 1)
+
+	// a solid chunk above blocks the sky
+	upper := solidChunk(size, 0, 1, 0)
+	world.Insert(upper)
+	assertLight(t, lower, 1, 0, 1, 0)
+
+	relit := make(map[ChunkPos]bool)
+	world.Subscribe(func(e ChunkEvent) {
+		if e.Type == ChunkRelit {
+			relit[e.Chunk] = true
+		}
+	})
+
+	// drilling through the upper chunk lets light back into the lower chunk
+	for y := size; y < 2*size; y++ {
+		world.Set(1, y, 1, EmptyVoxel)
+	}
+	assertLight(t, lower, 1, 0, 1, 1)
+	assertLight(t, lower, 1, size, 1, 1)
+	if !relit[ChunkPos{0, 0, 0}] {
+		t.Errorf("expected the lower chunk to be relit")
+	}
+}
+
+func TestBlockLightAcrossChunkBorder(t *testing.T) {
+	world := testWorld(newMemoryStore())
+	size := world.ChunkSize
+	a := solidChunk(size, 0, 0, 0)
+	b := solidChunk(size, 1, 0, 0)
+
+	// a sealed tunnel along the x axis, spanning both chunks
+	for x := 0; x < size; x++ {
+		a.Set(x, 1, 1, EmptyVoxel)
+		b.Set(x, 1, 1, EmptyVoxel)
+	}
+	world.Insert(a)
+	world.Insert(b)
+	if c := b.Light.Get(0, 1, 1).Color; c != [3]byte{} {
+		t.Errorf("expected dark tunnel, got %v", c)
+	}
+
+	// a red lamp at the start of the tunnel
+	lamp := Voxel{Block: LampBlock, R: 255, G: 0, B: 0}
+	radius := Block(LampBlock).Light
+	world.Set(0, 1, 1, lamp)
+	for x := 1; x < size; x++ {
+		expected := [3]byte{radius - byte(x), 0, 0}
+		if c := a.Light.Get(x, 1, 1).Color; c != expected {
+			t.Errorf("expected block light %v at x = %d, got %v", expected, x, c)
+		}
+	}
+	if c := b.Light.Get(0, 1, 1).Color; c != [3]byte{radius - byte(size), 0, 0} {
+		t.Errorf("expected block light to cross into the next chunk, got %v", c)
+	}
+
+	// removing the lamp darkens both chunks
+	world.Set(0, 1, 1, EmptyVoxel)
+	if c := b.Light.Get(0, 1, 1).Color; c != [3]byte{} {
+		t.Errorf("expected tunnel to go dark, got %v", c)
+	}
+}
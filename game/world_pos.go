@@ -0,0 +1,82 @@
+package game
+
+import (
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// WorldPos is an integer voxel position in world space
+type WorldPos struct {
+	X, Y, Z int
+}
+
+// LocalPos is an integer voxel position relative to the origin of its chunk.
+// Each component is always in the range [0, chunk size)
+type LocalPos struct {
+	X, Y, Z int
+}
+
+// WorldPosAt returns the position of the voxel containing the given point
+func WorldPosAt(p vec3.T) WorldPos {
+	f := p.Floor()
+	return WorldPos{int(f.X), int(f.Y), int(f.Z)}
+}
+
+// Chunk returns the position of the chunk containing this voxel
+func (p WorldPos) Chunk(size int) ChunkPos {
+	return ChunkPos{
+		X: floorDiv(p.X, size),
+		Y: floorDiv(p.Y, size),
+		Z: floorDiv(p.Z, size),
+	}
+}
+
+// Local returns the position of this voxel relative to its chunk
+func (p WorldPos) Local(size int) LocalPos {
+	return LocalPos{
+		X: floorMod(p.X, size),
+		Y: floorMod(p.Y, size),
+		Z: floorMod(p.Z, size),
+	}
+}
+
+// Split returns both the chunk position and the chunk local position of this voxel
+func (p WorldPos) Split(size int) (ChunkPos, LocalPos) {
+	return p.Chunk(size), p.Local(size)
+}
+
+// Vec3 returns the world space position of the voxels lower corner
+func (p WorldPos) Vec3() vec3.T {
+	return vec3.NewI(p.X, p.Y, p.Z)
+}
+
+// Origin returns the world position of the chunks first voxel
+func (c ChunkPos) Origin(size int) WorldPos {
+	return WorldPos{c.X * size, c.Y * size, c.Z * size}
+}
+
+// World converts a chunk local position back into world space
+func (c ChunkPos) World(size int, l LocalPos) WorldPos {
+	return WorldPos{
+		X: c.X*size + l.X,
+		Y: c.Y*size + l.Y,
+		Z: c.Z*size + l.Z,
+	}
+}
+
+// floorDiv divides a by b, rounding towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// floorMod returns the remainder of floorDiv(a, b), which always has the same sign as b
+func floorMod(a, b int) int {
+	m := a % b
+	if m != 0 && ((m < 0) != (b < 0)) {
+		m += b
+	}
+	return m
+}
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+func TestWorldPosSplit(t *testing.T) {
+	size := 16
+	cases := []struct {
+		Name  string
+		World WorldPos
+		Chunk ChunkPos
+		Local LocalPos
+	}{
+		{"origin", WorldPos{0, 0, 0}, ChunkPos{0, 0, 0}, LocalPos{0, 0, 0}},
+		{"positive", WorldPos{17, 5, 33}, ChunkPos{1, 0, 2}, LocalPos{1, 5, 1}},
+		{"positive edge", WorldPos{15, 16, 31}, ChunkPos{0, 1, 1}, LocalPos{15, 0, 15}},
+		{"negative x", WorldPos{-1, 3, 4}, ChunkPos{-1, 0, 0}, LocalPos{15, 3, 4}},
+		{"negative z", WorldPos{2, 3, -1}, ChunkPos{0, 0, -1}, LocalPos{2, 3, 15}},
+		{"negative xz", WorldPos{-17, 3, -16}, ChunkPos{-2, 0, -1}, LocalPos{15, 3, 0}},
+		{"negative y", WorldPos{1, -1, 1}, ChunkPos{0, -1, 0}, LocalPos{1, 15, 1}},
+		{"positive x negative z", WorldPos{20, 0, -20}, ChunkPos{1, 0, -2}, LocalPos{4, 0, 12}},
+		{"negative x positive z", WorldPos{-20, 0, 20}, ChunkPos{-2, 0, 1}, LocalPos{12, 0, 4}},
+		{"negative all", WorldPos{-32, -33, -48}, ChunkPos{-2, -3, -3}, LocalPos{0, 15, 0}},
+	}
+
+	for _, c := range cases {
+		chunk, local := c.World.Split(size)
+		if chunk != c.Chunk {
+			t.Errorf("%s: expected chunk %v, was %v", c.Name, c.Chunk, chunk)
+		}
+		if local != c.Local {
+			t.Errorf("%s: expected local position %v, was %v", c.Name, c.Local, local)
+		}
+		if back := chunk.World(size, local); back != c.World {
+			t.Errorf("%s: round trip returned %v", c.Name, back)
+		}
+	}
+}
+
+func TestWorldPosAt(t *testing.T) {
+	cases := []struct {
+		Point    vec3.T
+		Expected WorldPos
+	}{
+		{vec3.New(0.5, 0.5, 0.5), WorldPos{0, 0, 0}},
+		{vec3.New(-0.5, 1.5, -0.01), WorldPos{-1, 1, -1}},
+		{vec3.New(-1, -2, 3.99), WorldPos{-1, -2, 3}},
+	}
+	for _, c := range cases {
+		if pos := WorldPosAt(c.Point); pos != c.Expected {
+			t.Errorf("expected %v at %v, was %v", c.Expected, c.Point, pos)
+		}
+	}
+}
+
+type emptyProvider struct{}
+
+func (emptyProvider) Chunk(cx, cy, cz int) *Chunk { return NewChunk(4, 0, cx, cy, cz) }
+func (emptyProvider) Voxel(x, y, z int) Voxel     { return EmptyVoxel }
+
+func TestWorldSetNegative(t *testing.T) {
+	size := 4
+	world := &World{
+		ChunkSize: size,
+		Cache:     make(map[ChunkPos]*Chunk),
+		Provider:  emptyProvider{},
+	}
+	for _, cp := range []ChunkPos{{-1, 0, -1}, {0, 0, -1}, {-1, 0, 0}, {0, 0, 0}} {
+		world.Cache[cp] = NewChunk(size, 0, cp.X, cp.Y, cp.Z)
+	}
+
+	red := Voxel{Block: ColorBlock, R: 255}
+	points := []WorldPos{{-1, 1, -1}, {3, 2, -4}, {-4, 0, 3}, {1, 1, 1}}
+	for _, p := range points {
+		world.Set(p.X, p.Y, p.Z, red)
+		if v := world.Voxel(p.X, p.Y, p.Z); v != red {
+			t.Errorf("expected voxel at %v to be set, was %v", p, v)
+		}
+
+		cp, lp := p.Split(size)
+		if v := world.Cache[cp].At(lp.X, lp.Y, lp.Z); v != red {
+			t.Errorf("expected voxel %v to be stored in chunk %v at %v", p, cp, lp)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	"fmt"
+)
+
+// MarkDirty flags a chunk as modified. It will be saved once the world has been
+// left unedited for SaveDelay seconds, when it is unloaded, or when the world is flushed.
+func (w *World) MarkDirty(chunk *Chunk) {
+	if w.dirty == nil {
+		w.dirty = make(map[ChunkPos]*Chunk)
+	}
+	if len(w.dirty) == 0 {
+		w.dirtyTime = 0
+	}
+	w.dirty[ChunkPos{chunk.Cx, chunk.Cy, chunk.Cz}] = chunk
+	w.quietTime = 0
+}
+
+// Dirty returns true if the chunk at the given position has unsaved changes
+func (w *World) Dirty(cp ChunkPos) bool {
+	_, dirty := w.dirty[cp]
+	return dirty
+}
+
+// DirtyCount returns the number of chunks with unsaved changes
+func (w *World) DirtyCount() int {
+	return len(w.dirty)
+}
+
+// Update advances the fluid simulation, then advances the autosave timers and flushes dirty
+// chunks once they are due. Returns any error that occured while saving.
+func (w *World) Update(dt float32) error {
+	w.SimulateFluids(dt)
+	if len(w.dirty) == 0 {
+		return nil
+	}
+
+	w.quietTime += dt
+	w.dirtyTime += dt
+	if w.quietTime < w.SaveDelay && w.dirtyTime < w.MaxSaveDelay {
+		return nil
+	}
+	return w.Flush()
+}
+
+// Flush writes all dirty chunks to the store in a single batch. Chunks that fail to save remain dirty.
+func (w *World) Flush() error {
+	chunks := make([]*Chunk, 0, len(w.dirty))
+	for _, chunk := range w.dirty {
+		chunks = append(chunks, chunk)
+	}
+
+	var first error
+	failed := 0
+	for i, err := range w.Store.SaveAll(chunks) {
+		chunk := chunks[i]
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("error saving chunk %d,%d,%d: %w", chunk.Cx, chunk.Cy, chunk.Cz, err)
+			}
+			failed++
+			continue
+		}
+		delete(w.dirty, ChunkPos{chunk.Cx, chunk.Cy, chunk.Cz})
+	}
+
+	w.quietTime = 0
+	w.dirtyTime = 0
+
+	if failed > 1 {
+		return fmt.Errorf("%w (and %d more)", first, failed-1)
+	}
+	return first
+}
+
+// Save writes every loaded chunk to the store, regardless of whether it has been modified.
+func (w *World) Save() error {
+	for _, chunk := range w.Cache {
+		w.MarkDirty(chunk)
+	}
+	return w.Flush()
+}
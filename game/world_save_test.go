@@ -0,0 +1,138 @@
+package game
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// memoryStore is an in-memory chunk store that counts writes
+type memoryStore struct {
+	chunks map[ChunkPos]*Chunk
+	saves  int
+	fail   bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{chunks: make(map[ChunkPos]*Chunk)}
+}
+
+func (s *memoryStore) Load(cx, cy, cz int) (*Chunk, error) {
+	if chunk, exists := s.chunks[ChunkPos{cx, cy, cz}]; exists {
+		return chunk, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (s *memoryStore) Save(chunk *Chunk) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	s.saves++
+	s.chunks[ChunkPos{chunk.Cx, chunk.Cy, chunk.Cz}] = chunk
+	return nil
+}
+
+func (s *memoryStore) SaveAll(chunks []*Chunk) []error {
+	errs := make([]error, len(chunks))
+	for i, chunk := range chunks {
+		errs[i] = s.Save(chunk)
+	}
+	return errs
+}
+
+func testWorld(store ChunkStore) *World {
+	world := newWorld(1, 4, emptyProvider{})
+	world.Store = store
+	return world
+}
+
+func TestWorldAutosaveDebounce(t *testing.T) {
+	store := newMemoryStore()
+	world := testWorld(store)
+	world.AddChunk(0, 0, 0)
+	if store.saves != 0 {
+		t.Fatal("expected generated chunks not to be saved")
+	}
+
+	// continuous edits postpone saving
+	for i := 0; i < 8; i++ {
+		world.Set(1, 1, 1, Voxel{Block: ColorBlock, R: byte(i + 1)})
+		if err := world.Update(world.SaveDelay / 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.saves != 0 {
+		t.Errorf("expected no saves during continuous editing, got %d", store.saves)
+	}
+
+	// until the maximum delay is reached
+	for i := 0; i < 20; i++ {
+		world.Set(1, 1, 1, Voxel{Block: ColorBlock, G: byte(i + 1)})
+		world.Update(world.SaveDelay / 2)
+	}
+	if store.saves == 0 {
+		t.Error("expected chunk to be saved once the maximum delay passed")
+	}
+
+	saves := store.saves
+	world.Set(2, 2, 2, Voxel{Block: ColorBlock, B: 1})
+	world.Update(world.SaveDelay)
+	if store.saves != saves+1 || world.DirtyCount() != 0 {
+		t.Error("expected chunk to be saved after the world was left unedited")
+	}
+
+	world.Update(world.SaveDelay)
+	if store.saves != saves+1 {
+		t.Error("expected clean chunks not to be saved again")
+	}
+}
+
+func TestWorldFlushErrors(t *testing.T) {
+	store := newMemoryStore()
+	world := testWorld(store)
+	world.AddChunk(0, 0, 0)
+	world.AddChunk(1, 0, 0)
+	world.Set(1, 1, 1, Voxel{Block: ColorBlock, R: 1})
+	world.Set(5, 1, 1, Voxel{Block: ColorBlock, R: 1})
+
+	store.fail = true
+	if err := world.Flush(); err == nil {
+		t.Fatal("expected flush to report errors")
+	}
+	if world.DirtyCount() != 2 {
+		t.Errorf("expected failed chunks to remain dirty, %d were", world.DirtyCount())
+	}
+	if err := world.Unload(ChunkPos{0, 0, 0}); err == nil {
+		t.Error("expected unload to report save errors")
+	}
+
+	store.fail = false
+	if err := world.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if world.DirtyCount() != 0 || store.saves != 2 {
+		t.Errorf("expected both chunks to be saved on retry, saved %d", store.saves)
+	}
+}
+
+func TestWorldUnloadSaves(t *testing.T) {
+	store := newMemoryStore()
+	world := testWorld(store)
+	world.AddChunk(0, 0, 0)
+	world.Set(1, 1, 1, Voxel{Block: ColorBlock, R: 1})
+
+	if err := world.Unload(ChunkPos{0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if store.saves != 1 {
+		t.Error("expected dirty chunk to be saved on unload")
+	}
+	chunk, err := world.AddChunk(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.At(1, 1, 1) != (Voxel{Block: ColorBlock, R: 1}) {
+		t.Error("expected edits to survive unloading")
+	}
+}
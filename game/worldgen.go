@@ -1,81 +1,308 @@
 package game
 
 import (
+	"sync"
+
 	"github.com/johanhenriksson/goworld/math"
 )
 
+// WorldGenerator generates terrain from a graph of noise sources. Temperature and humidity noise
+// select the biome of each column, and the terrain height is blended between neighboring biomes.
 type WorldGenerator struct {
-	Seed     int
-	Size     int
-	Rock     *math.Noise
-	Grass    *math.Noise
-	Cave     *math.Noise
-	Variance *math.Noise
+	Seed   int
+	Size   int
+	Biomes []*Biome
+
+	Temperature math.NoiseSource
+	Humidity    math.NoiseSource
+	Hills       math.NoiseSource
+	Ridges      math.NoiseSource
+	Cave        math.NoiseSource
+	Variance    math.NoiseSource
+
+	// CaveThreshold is the value of the cave noise above which terrain is carved out
+	CaveThreshold float32
+
+	// cells caches built structure cells, since each structure is needed by every chunk
+	// and voxel query it overlaps. Chunks are generated concurrently, so it is guarded by cellLock.
+	cells    map[[2]int]*structureCell
+	cellLock sync.Mutex
 }
 
-func ExampleWorldgen(seed, size int) *WorldGenerator {
-	return &WorldGenerator{
-		Seed:     seed,
-		Size:     size,
-		Rock:     math.NewNoise(seed+10000, 1.0/40.0),
-		Grass:    math.NewNoise(seed+10002, 1.0/28.0),
-		Cave:     math.NewNoise(seed+18002, 1.0/14.0),
-		Variance: math.NewNoise(seed+12004, 1.0/0.5),
-	}
+// StructureCellSize is the width of the square cells of columns the world is divided into for
+// structure placement. At most one structure is anchored in each cell.
+const StructureCellSize = 8
+
+// structureSalt separates the hashes used for structure placement from column features
+const structureSalt = 0x5700
+
+// maxStructureCells is the number of built structure cells kept by a generator.
+// The cache is cleared once it is full.
+const maxStructureCells = 4096
+
+// structureCell holds the voxels of the structure anchored in a structure cell, if any
+type structureCell struct {
+	Anchor WorldPos
+	Extent int
+	Voxels []structureVoxel
 }
 
-func (wg *WorldGenerator) Chunk(cx, cz int) *Chunk {
-	chunk := NewChunk(wg.Size, wg.Seed, cx, cz)
+// structureVoxel is a voxel placed by a structure, in the order it was placed
+type structureVoxel struct {
+	Position WorldPos
+	Voxel    Voxel
+}
+
+// terrainColumn holds the properties of a column of generated terrain
+type terrainColumn struct {
+	Height        int
+	Biome         *Biome
+	Carved        bool
+	Feature       *ColumnFeature
+	FeatureHeight int
+}
+
+// Chunk generates the terrain of a chunk, followed by the parts of every structure reaching into it.
+// Structures are rebuilt from the seed by each chunk they overlap, so parts overflowing into
+// neighboring chunks are carried over no matter which chunk is generated first.
+func (wg *WorldGenerator) Chunk(cx, cy, cz int) *Chunk {
+	chunk := NewChunk(wg.Size, wg.Seed, cx, cy, cz)
 	for z := 0; z < chunk.Sz; z++ {
-		for y := 0; y < chunk.Sy; y++ {
-			for x := 0; x < chunk.Sx; x++ {
-				voxel := wg.Voxel(chunk.Ox+x, chunk.Oy+y, chunk.Oz+z)
+		for x := 0; x < chunk.Sx; x++ {
+			column := wg.column(chunk.Ox+x, chunk.Oz+z)
+			for y := 0; y < chunk.Sy; y++ {
+				voxel := wg.voxel(column, chunk.Ox+x, chunk.Oy+y, chunk.Oz+z)
 				chunk.Set(x, y, z, voxel)
-				if voxel != EmptyVoxel {
-					chunk.Light.Block(x, y, z, true)
-				}
 			}
 		}
 	}
+
+	wg.structures(chunk.Ox, chunk.Oz, chunk.Ox+chunk.Sx-1, chunk.Oz+chunk.Sz-1, func(p WorldPos, v Voxel) {
+		x, y, z := p.X-chunk.Ox, p.Y-chunk.Oy, p.Z-chunk.Oz
+		if chunk.Contains(x, y, z) {
+			chunk.Set(x, y, z, v)
+		}
+	})
+
 	chunk.Light.Calculate()
-	go chunk.Write("chunks")
 	return chunk
 }
 
+// Voxel returns the generated voxel at the given world position, including structures
 func (wg *WorldGenerator) Voxel(x, y, z int) Voxel {
-	rock2 := Voxel{R: 137, G: 131, B: 119}
-	rock := Voxel{R: 173, G: 169, B: 158}
-	grass := Voxel{R: 72, G: 140, B: 54}
+	voxel := wg.voxel(wg.column(x, z), x, y, z)
+	target := WorldPos{x, y, z}
+	wg.structures(x, z, x, z, func(p WorldPos, v Voxel) {
+		if p == target {
+			voxel = v
+		}
+	})
+	return voxel
+}
 
-	gh := int(9 * wg.Grass.Sample(x, y, z))
-	rh := int(44 * wg.Rock.Sample(x, y, z))
-	grassHeight := 8
+// BiomeAt returns the biome of the column at the given world position
+func (wg *WorldGenerator) BiomeAt(x, z int) *Biome {
+	temperature, humidity := wg.climate(float32(x), float32(z))
+	biome, _ := wg.nearestBiome(temperature, humidity)
+	return biome
+}
 
-	var vtype Voxel
-	if y < grassHeight {
-		vtype = rock2
+// climate returns the temperature and humidity at the given world position
+func (wg *WorldGenerator) climate(x, z float32) (float32, float32) {
+	return wg.Temperature.Sample2(x, z), wg.Humidity.Sample2(x, z)
+}
+
+// nearestBiome returns the biome closest to the given climate, and its distance to it
+func (wg *WorldGenerator) nearestBiome(temperature, humidity float32) (*Biome, float32) {
+	var nearest *Biome
+	min := math.InfPos
+	for _, biome := range wg.Biomes {
+		if d := biome.climateDistance(temperature, humidity); d < min {
+			nearest, min = biome, d
+		}
+	}
+	return nearest, min
+}
+
+// column computes the biome, terrain height and surface feature of a column. The height is a
+// weighted average of the height profiles of every biome within BiomeBlend of the nearest one,
+// so that the terrain is continuous across biome borders.
+func (wg *WorldGenerator) column(x, z int) terrainColumn {
+	fx, fz := float32(x), float32(z)
+	temperature, humidity := wg.climate(fx, fz)
+	nearest, min := wg.nearestBiome(temperature, humidity)
+	hills := wg.Hills.Sample2(fx, fz)
+	ridges := wg.Ridges.Sample2(fx, fz)
+
+	height, total := float32(0), float32(0)
+	for _, biome := range wg.Biomes {
+		d := biome.climateDistance(temperature, humidity) - min
+		if d >= BiomeBlend {
+			continue
+		}
+		weight := 1 - d/BiomeBlend
+		weight *= weight
+		height += weight * (biome.Height + biome.Hills*hills + biome.Ridges*ridges)
+		total += weight
 	}
 
-	if y == grassHeight {
-		vtype = grass
+	column := terrainColumn{
+		Height: int(math.Floor(height / total)),
+		Biome:  nearest,
 	}
-	if y <= grassHeight+gh && y > grassHeight {
-		vtype = grass
+
+	// features are only placed on surfaces that have not been carved out by caves
+	if wg.Cave.Sample3(fx, float32(column.Height), fz) > wg.CaveThreshold {
+		column.Carved = true
+		return column
 	}
-	if y < rh {
-		vtype = rock
+	for i := range nearest.Features {
+		feature := &nearest.Features[i]
+		roll := columnHash(wg.Seed, x, z, uint32(2*i))
+		if hashFloat(roll) >= feature.Chance {
+			continue
+		}
+		column.Feature = feature
+		column.FeatureHeight = feature.MinHeight
+		if span := feature.MaxHeight - feature.MinHeight + 1; span > 1 {
+			column.FeatureHeight += int(columnHash(wg.Seed, x, z, uint32(2*i+1)) % uint32(span))
+		}
+		break
 	}
+	return column
+}
+
+// voxel returns the voxel at the given height of a terrain column
+func (wg *WorldGenerator) voxel(column terrainColumn, x, y, z int) Voxel {
+	biome := column.Biome
 
-	if wg.Cave.Sample(x, y, z) > 0.5 {
-		vtype = EmptyVoxel
+	var vtype Voxel
+	switch {
+	case y > column.Height+column.FeatureHeight:
+		return EmptyVoxel
+	case y > column.Height:
+		vtype = column.Feature.Voxel
+	case y == column.Height:
+		vtype = biome.Surface
+	case y > column.Height-biome.SoilDepth:
+		vtype = biome.Soil
+	default:
+		vtype = biome.Stone
 	}
 
-	if vtype != EmptyVoxel {
-		l := 1 - 0.3 * (wg.Variance.Sample(x, y, z) + 1)/2
-		vtype.R = byte(l * float32(vtype.R))
-		vtype.G = byte(l * float32(vtype.G))
-		vtype.B = byte(l * float32(vtype.B))
+	fx, fy, fz := float32(x), float32(y), float32(z)
+	if y <= column.Height && wg.Cave.Sample3(fx, fy, fz) > wg.CaveThreshold {
+		return EmptyVoxel
 	}
 
+	l := 1 - 0.3*(wg.Variance.Sample3(fx, fy, fz)+1)/2
+	vtype.R = byte(l * float32(vtype.R))
+	vtype.G = byte(l * float32(vtype.G))
+	vtype.B = byte(l * float32(vtype.B))
 	return vtype
 }
+
+// structures places every structure that may reach into the given range of columns.
+// Structures are placed in a fixed order, so overlapping structures always resolve the same way.
+func (wg *WorldGenerator) structures(x0, z0, x1, z1 int, set func(WorldPos, Voxel)) {
+	extent := wg.structureExtent()
+	if extent < 0 {
+		return
+	}
+	size := StructureCellSize
+	for cz := floorDiv(z0-extent, size); cz <= floorDiv(z1+extent, size); cz++ {
+		for cx := floorDiv(x0-extent, size); cx <= floorDiv(x1+extent, size); cx++ {
+			cell := wg.structureCell(cx, cz)
+			if cell == nil {
+				continue
+			}
+			a, e := cell.Anchor, cell.Extent
+			if a.X+e < x0 || a.X-e > x1 || a.Z+e < z0 || a.Z-e > z1 {
+				continue
+			}
+			for _, v := range cell.Voxels {
+				set(v.Position, v.Voxel)
+			}
+		}
+	}
+}
+
+// structureCell returns the built structure of the given structure cell, or nil if it has none.
+// Cells are built once and cached.
+func (wg *WorldGenerator) structureCell(cx, cz int) *structureCell {
+	key := [2]int{cx, cz}
+	wg.cellLock.Lock()
+	cell, cached := wg.cells[key]
+	wg.cellLock.Unlock()
+	if cached {
+		return cell
+	}
+
+	if placement, anchor, exists := wg.structureIn(cx, cz); exists {
+		cell = &structureCell{Anchor: anchor, Extent: placement.Structure.Extent()}
+		rnd := StructureRand(columnHash(wg.Seed, cx, cz, structureSalt+2))
+		placement.Structure.Build(anchor, &rnd, func(p WorldPos, v Voxel) {
+			cell.Voxels = append(cell.Voxels, structureVoxel{p, v})
+		})
+	}
+
+	wg.cellLock.Lock()
+	if wg.cells == nil || len(wg.cells) >= maxStructureCells {
+		wg.cells = make(map[[2]int]*structureCell)
+	}
+	wg.cells[key] = cell
+	wg.cellLock.Unlock()
+	return cell
+}
+
+// structureIn returns the structure anchored in the given structure cell, and its anchor position.
+// The anchor is a random column of the cell, and the structure is picked from its biome.
+func (wg *WorldGenerator) structureIn(cx, cz int) (*StructurePlacement, WorldPos, bool) {
+	size := uint32(StructureCellSize)
+	h := columnHash(wg.Seed, cx, cz, structureSalt)
+	x := cx*StructureCellSize + int(h%size)
+	z := cz*StructureCellSize + int(h/size%size)
+	column := wg.column(x, z)
+	if column.Carved {
+		return nil, WorldPos{}, false
+	}
+
+	roll := hashFloat(columnHash(wg.Seed, cx, cz, structureSalt+1))
+	for i := range column.Biome.Structures {
+		placement := &column.Biome.Structures[i]
+		if roll < placement.Chance {
+			return placement, WorldPos{x, column.Height + 1, z}, true
+		}
+		roll -= placement.Chance
+	}
+	return nil, WorldPos{}, false
+}
+
+// structureExtent returns the largest extent of any structure in any biome, or -1 if there are none
+func (wg *WorldGenerator) structureExtent() int {
+	extent := -1
+	for _, biome := range wg.Biomes {
+		for _, placement := range biome.Structures {
+			if e := placement.Structure.Extent(); e > extent {
+				extent = e
+			}
+		}
+	}
+	return extent
+}
+
+// columnHash deterministically hashes a world column and a salt value
+func columnHash(seed, x, z int, salt uint32) uint32 {
+	h := uint32(seed)*0x9e3779b9 ^ uint32(x)*0x85ebca6b ^ uint32(z)*0xc2b2ae35 ^ salt*0x27d4eb2f
+	h ^= h >> 16
+	h *= 0x7feb352d
+	h ^= h >> 15
+	h *= 0x846ca68b
+	h ^= h >> 16
+	return h
+}
+
+// hashFloat maps a hash to the range [0, 1)
+func hashFloat(h uint32) float32 {
+	return float32(h>>8) / float32(1<<24)
+}
@@ -0,0 +1,545 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/johanhenriksson/goworld/math"
+)
+
+// WorldgenPresetDir is the directory worldgen presets are loaded from
+var WorldgenPresetDir = "assets/worldgen"
+
+// WorldgenPreset json representation. A preset describes the noise graph, voxel palette,
+// structures and biomes of a world generator, so that terrain can be tuned without rebuilding.
+type WorldgenPreset struct {
+	Name string `json:"-"`
+
+	// Climate noise selects the biome of each column
+	Temperature *NoiseDefinition
+	Humidity    *NoiseDefinition
+
+	// Terrain noise shapes the height profile of each biome
+	Hills  *NoiseDefinition
+	Ridges *NoiseDefinition
+
+	// Cave noise carves out terrain where it exceeds CaveThreshold
+	Cave          *NoiseDefinition
+	CaveThreshold float32
+
+	// Variance noise tints the color of each voxel
+	Variance *NoiseDefinition
+
+	// Palette names the voxels used by biomes and structures
+	Palette map[string]*VoxelDefinition
+
+	// Structures names the structures that may be placed by biomes
+	Structures map[string]*StructureDefinition
+
+	Biomes []*BiomeDefinition
+}
+
+// NoiseDefinition json representation. Type selects the kind of noise node, and determines
+// which of the remaining fields are used:
+//
+//	simplex:               Seed, Scale
+//	constant:              Value
+//	fbm, ridged, billow:   Source, Octaves, Lacunarity, Gain
+//	warp:                  Source, Offset, Amount
+//	add, multiply:         Sources
+//	clamp:                 Source, Min, Max
+//	remap:                 Source, From, To
+//	select:                Control, Low, High, Threshold, Falloff
+type NoiseDefinition struct {
+	Type string
+
+	// Seed is added to the world seed
+	Seed int
+
+	// Scale is the size of noise features, in voxels
+	Scale float32
+
+	Value float32
+
+	Source  *NoiseDefinition
+	Sources []*NoiseDefinition
+	Offset  *NoiseDefinition
+	Control *NoiseDefinition
+	Low     *NoiseDefinition
+	High    *NoiseDefinition
+
+	Octaves    int
+	Lacunarity float32
+	Gain       float32
+	Amount     float32
+	Min        float32
+	Max        float32
+	From       [2]float32
+	To         [2]float32
+	Threshold  float32
+	Falloff    float32
+}
+
+// VoxelDefinition json representation
+type VoxelDefinition struct {
+	// Block is the name of the block type. Defaults to Color.
+	Block string
+	Color [3]byte
+}
+
+// StructureDefinition json representation. Type is one of tree, boulder or ruin.
+// Voxel fields refer to palette entries.
+type StructureDefinition struct {
+	Type string
+
+	// tree
+	Trunk     string
+	Leaves    string
+	MinHeight int
+	MaxHeight int
+	Crown     int
+
+	// boulder
+	Rock      string
+	MinRadius int
+	MaxRadius int
+
+	// ruin
+	Wall   string
+	Size   int
+	Height int
+}
+
+// BiomeDefinition json representation. Voxel fields refer to palette entries.
+type BiomeDefinition struct {
+	Name        string
+	Temperature float32
+	Humidity    float32
+	Surface     string
+	Soil        string
+	SoilDepth   int
+	Stone       string
+	Height      float32
+	Hills       float32
+	Ridges      float32
+	Features    []*FeatureDefinition
+	Structures  []*PlacementDefinition
+}
+
+// FeatureDefinition json representation
+type FeatureDefinition struct {
+	Voxel     string
+	Chance    float32
+	MinHeight int
+	MaxHeight int
+}
+
+// PlacementDefinition json representation
+type PlacementDefinition struct {
+	Structure string
+	Chance    float32
+}
+
+// PresetError lists every problem found in a worldgen preset
+type PresetError struct {
+	Preset   string
+	Problems []string
+}
+
+func (e *PresetError) Error() string {
+	return fmt.Sprintf("invalid worldgen preset %s: %s", e.Preset, strings.Join(e.Problems, "; "))
+}
+
+// LoadWorldgenPreset loads and validates a preset from WorldgenPresetDir
+func LoadWorldgenPreset(name string) (*WorldgenPreset, error) {
+	data, err := ioutil.ReadFile(filepath.Join(WorldgenPresetDir, name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load worldgen preset %s: %w", name, err)
+	}
+	return ParseWorldgenPreset(name, data)
+}
+
+// ParseWorldgenPreset parses and validates a json preset. Unknown fields are rejected,
+// since they are most likely misspelled.
+func ParseWorldgenPreset(name string, data []byte) (*WorldgenPreset, error) {
+	preset := &WorldgenPreset{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(preset); err != nil {
+		return nil, &PresetError{Preset: name, Problems: []string{err.Error()}}
+	}
+	preset.Name = name
+	if err := preset.Validate(); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+// Validate checks the preset for missing, unknown or out of range values.
+// All problems are collected into a single PresetError.
+func (p *WorldgenPreset) Validate() error {
+	v := &presetValidator{preset: p}
+
+	v.noise("Temperature", p.Temperature)
+	v.noise("Humidity", p.Humidity)
+	v.noise("Hills", p.Hills)
+	v.noise("Ridges", p.Ridges)
+	v.noise("Cave", p.Cave)
+	v.noise("Variance", p.Variance)
+
+	for _, name := range sortedKeys(p.Palette) {
+		def := p.Palette[name]
+		if def == nil {
+			v.fail("Palette."+name, "missing voxel")
+			continue
+		}
+		if _, exists := def.block(); !exists {
+			v.fail("Palette."+name+".Block", "unknown block %q", def.Block)
+		}
+	}
+
+	for _, name := range sortedKeys(p.Structures) {
+		v.structure("Structures."+name, p.Structures[name])
+	}
+
+	if len(p.Biomes) == 0 {
+		v.fail("Biomes", "at least one biome is required")
+	}
+	names := map[string]bool{}
+	for i, biome := range p.Biomes {
+		v.biome(fmt.Sprintf("Biomes[%d]", i), biome, names)
+	}
+
+	if len(v.problems) > 0 {
+		return &PresetError{Preset: p.Name, Problems: v.problems}
+	}
+	return nil
+}
+
+// Generator creates a world generator from the preset
+func (p *WorldgenPreset) Generator(seed, size int) (*WorldGenerator, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	structures := make(map[string]Structure, len(p.Structures))
+	for name, def := range p.Structures {
+		structures[name] = def.structure(p)
+	}
+
+	biomes := make([]*Biome, len(p.Biomes))
+	for i, def := range p.Biomes {
+		biome := &Biome{
+			Name:        def.Name,
+			Temperature: def.Temperature,
+			Humidity:    def.Humidity,
+			Surface:     p.voxel(def.Surface),
+			Soil:        p.voxel(def.Soil),
+			SoilDepth:   def.SoilDepth,
+			Stone:       p.voxel(def.Stone),
+			Height:      def.Height,
+			Hills:       def.Hills,
+			Ridges:      def.Ridges,
+		}
+		for _, feature := range def.Features {
+			biome.Features = append(biome.Features, ColumnFeature{
+				Voxel:     p.voxel(feature.Voxel),
+				Chance:    feature.Chance,
+				MinHeight: feature.MinHeight,
+				MaxHeight: feature.MaxHeight,
+			})
+		}
+		for _, placement := range def.Structures {
+			biome.Structures = append(biome.Structures, StructurePlacement{
+				Structure: structures[placement.Structure],
+				Chance:    placement.Chance,
+			})
+		}
+		biomes[i] = biome
+	}
+
+	return &WorldGenerator{
+		Seed:          seed,
+		Size:          size,
+		Biomes:        biomes,
+		Temperature:   p.Temperature.source(seed),
+		Humidity:      p.Humidity.source(seed),
+		Hills:         p.Hills.source(seed),
+		Ridges:        p.Ridges.source(seed),
+		Cave:          p.Cave.source(seed),
+		Variance:      p.Variance.source(seed),
+		CaveThreshold: p.CaveThreshold,
+	}, nil
+}
+
+// voxel returns the palette entry with the given name
+func (p *WorldgenPreset) voxel(name string) Voxel {
+	def := p.Palette[name]
+	block, _ := def.block()
+	return Voxel{Block: block.ID, R: def.Color[0], G: def.Color[1], B: def.Color[2]}
+}
+
+// block returns the block type of the voxel
+func (d *VoxelDefinition) block() (*BlockType, bool) {
+	if d.Block == "" {
+		return Block(ColorBlock), true
+	}
+	return BlockByName(d.Block)
+}
+
+// source builds the noise graph described by the definition
+func (d *NoiseDefinition) source(seed int) math.NoiseSource {
+	switch d.Type {
+	case "simplex":
+		return math.NewNoise(seed+d.Seed, 1/d.Scale)
+	case "constant":
+		return math.Constant(d.Value)
+	case "fbm":
+		return math.NewFBM(d.Source.source(seed), d.Octaves, d.Lacunarity, d.Gain)
+	case "ridged":
+		return math.NewRidged(d.Source.source(seed), d.Octaves, d.Lacunarity, d.Gain)
+	case "billow":
+		return math.NewBillow(d.Source.source(seed), d.Octaves, d.Lacunarity, d.Gain)
+	case "warp":
+		return math.NewWarp(d.Source.source(seed), d.Offset.source(seed), d.Amount)
+	case "add", "multiply":
+		sources := make([]math.NoiseSource, len(d.Sources))
+		for i, source := range d.Sources {
+			sources[i] = source.source(seed)
+		}
+		if d.Type == "add" {
+			return math.Add(sources...)
+		}
+		return math.Multiply(sources...)
+	case "clamp":
+		return math.ClampNoise(d.Source.source(seed), d.Min, d.Max)
+	case "remap":
+		return math.Remap(d.Source.source(seed), d.From[0], d.From[1], d.To[0], d.To[1])
+	case "select":
+		return math.Select(d.Control.source(seed), d.Low.source(seed), d.High.source(seed), d.Threshold, d.Falloff)
+	}
+	panic(fmt.Errorf("unknown noise type %q", d.Type))
+}
+
+// structure creates the structure described by the definition
+func (d *StructureDefinition) structure(p *WorldgenPreset) Structure {
+	switch d.Type {
+	case "tree":
+		return &Tree{
+			Trunk:     p.voxel(d.Trunk),
+			Leaves:    p.voxel(d.Leaves),
+			MinHeight: d.MinHeight,
+			MaxHeight: d.MaxHeight,
+			Crown:     d.Crown,
+		}
+	case "boulder":
+		return &Boulder{Rock: p.voxel(d.Rock), MinRadius: d.MinRadius, MaxRadius: d.MaxRadius}
+	case "ruin":
+		return &Ruin{Wall: p.voxel(d.Wall), Size: d.Size, Height: d.Height}
+	}
+	panic(fmt.Errorf("unknown structure type %q", d.Type))
+}
+
+// presetValidator collects problems found in a preset, prefixed with the path of the offending field
+type presetValidator struct {
+	preset   *WorldgenPreset
+	problems []string
+}
+
+func (v *presetValidator) fail(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *presetValidator) noise(path string, d *NoiseDefinition) {
+	if d == nil {
+		v.fail(path, "missing noise")
+		return
+	}
+	switch d.Type {
+	case "simplex":
+		if d.Scale <= 0 {
+			v.fail(path+".Scale", "must be positive, was %g", d.Scale)
+		}
+	case "constant":
+	case "fbm", "ridged", "billow":
+		v.noise(path+".Source", d.Source)
+		if d.Octaves < 1 {
+			v.fail(path+".Octaves", "must be at least 1, was %d", d.Octaves)
+		}
+		if d.Lacunarity <= 0 {
+			v.fail(path+".Lacunarity", "must be positive, was %g", d.Lacunarity)
+		}
+		if d.Gain <= 0 {
+			v.fail(path+".Gain", "must be positive, was %g", d.Gain)
+		}
+	case "warp":
+		v.noise(path+".Source", d.Source)
+		v.noise(path+".Offset", d.Offset)
+	case "add", "multiply":
+		if len(d.Sources) == 0 {
+			v.fail(path+".Sources", "at least one source is required")
+		}
+		for i, source := range d.Sources {
+			v.noise(fmt.Sprintf("%s.Sources[%d]", path, i), source)
+		}
+	case "clamp":
+		v.noise(path+".Source", d.Source)
+		if d.Min > d.Max {
+			v.fail(path, "Min %g is greater than Max %g", d.Min, d.Max)
+		}
+	case "remap":
+		v.noise(path+".Source", d.Source)
+		if d.From[0] == d.From[1] {
+			v.fail(path+".From", "range must not be empty")
+		}
+	case "select":
+		v.noise(path+".Control", d.Control)
+		v.noise(path+".Low", d.Low)
+		v.noise(path+".High", d.High)
+		if d.Falloff < 0 {
+			v.fail(path+".Falloff", "must not be negative, was %g", d.Falloff)
+		}
+	case "":
+		v.fail(path+".Type", "missing noise type")
+	default:
+		v.fail(path+".Type", "unknown noise type %q", d.Type)
+	}
+}
+
+// voxel checks that a voxel field refers to a palette entry
+func (v *presetValidator) voxel(path, name string) {
+	if name == "" {
+		v.fail(path, "missing voxel")
+	} else if v.preset.Palette[name] == nil {
+		v.fail(path, "unknown palette entry %q", name)
+	}
+}
+
+func (v *presetValidator) structure(path string, d *StructureDefinition) {
+	if d == nil {
+		v.fail(path, "missing structure")
+		return
+	}
+	switch d.Type {
+	case "tree":
+		v.voxel(path+".Trunk", d.Trunk)
+		v.voxel(path+".Leaves", d.Leaves)
+		v.heights(path, d.MinHeight, d.MaxHeight)
+		if d.Crown < 0 {
+			v.fail(path+".Crown", "must not be negative, was %d", d.Crown)
+		}
+	case "boulder":
+		v.voxel(path+".Rock", d.Rock)
+		if d.MinRadius < 1 {
+			v.fail(path+".MinRadius", "must be at least 1, was %d", d.MinRadius)
+		}
+		if d.MaxRadius < d.MinRadius {
+			v.fail(path, "MaxRadius %d is less than MinRadius %d", d.MaxRadius, d.MinRadius)
+		}
+	case "ruin":
+		v.voxel(path+".Wall", d.Wall)
+		if d.Size < 2 {
+			v.fail(path+".Size", "must be at least 2, was %d", d.Size)
+		}
+		if d.Height < 0 {
+			v.fail(path+".Height", "must not be negative, was %d", d.Height)
+		}
+	case "":
+		v.fail(path+".Type", "missing structure type")
+	default:
+		v.fail(path+".Type", "unknown structure type %q", d.Type)
+	}
+}
+
+func (v *presetValidator) biome(path string, d *BiomeDefinition, names map[string]bool) {
+	if d == nil {
+		v.fail(path, "missing biome")
+		return
+	}
+	if d.Name == "" {
+		v.fail(path+".Name", "missing name")
+	} else if names[d.Name] {
+		v.fail(path+".Name", "duplicate biome %q", d.Name)
+	}
+	names[d.Name] = true
+
+	v.climate(path+".Temperature", d.Temperature)
+	v.climate(path+".Humidity", d.Humidity)
+	v.voxel(path+".Surface", d.Surface)
+	v.voxel(path+".Soil", d.Soil)
+	v.voxel(path+".Stone", d.Stone)
+	if d.SoilDepth < 0 {
+		v.fail(path+".SoilDepth", "must not be negative, was %d", d.SoilDepth)
+	}
+
+	for i, feature := range d.Features {
+		fpath := fmt.Sprintf("%s.Features[%d]", path, i)
+		if feature == nil {
+			v.fail(fpath, "missing feature")
+			continue
+		}
+		v.voxel(fpath+".Voxel", feature.Voxel)
+		v.chance(fpath+".Chance", feature.Chance)
+		v.heights(fpath, feature.MinHeight, feature.MaxHeight)
+	}
+
+	total := float32(0)
+	for i, placement := range d.Structures {
+		ppath := fmt.Sprintf("%s.Structures[%d]", path, i)
+		if placement == nil {
+			v.fail(ppath, "missing structure placement")
+			continue
+		}
+		if v.preset.Structures[placement.Structure] == nil {
+			v.fail(ppath+".Structure", "unknown structure %q", placement.Structure)
+		}
+		v.chance(ppath+".Chance", placement.Chance)
+		total += placement.Chance
+	}
+	if total > 1 {
+		v.fail(path+".Structures", "chances add up to %g, which is more than 1", total)
+	}
+}
+
+func (v *presetValidator) climate(path string, value float32) {
+	if value < -1 || value > 1 {
+		v.fail(path, "must be in the range [-1, 1], was %g", value)
+	}
+}
+
+func (v *presetValidator) chance(path string, value float32) {
+	if value < 0 || value > 1 {
+		v.fail(path, "must be in the range [0, 1], was %g", value)
+	}
+}
+
+func (v *presetValidator) heights(path string, min, max int) {
+	if min < 1 {
+		v.fail(path+".MinHeight", "must be at least 1, was %d", min)
+	}
+	if max < min {
+		v.fail(path, "MaxHeight %d is less than MinHeight %d", max, min)
+	}
+}
+
+// sortedKeys returns the keys of a preset map in sorted order, so that problems are reported consistently
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*VoxelDefinition:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*StructureDefinition:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
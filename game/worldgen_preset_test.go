@@ -0,0 +1,118 @@
+package game
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func loadTestPreset(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile("../assets/worldgen/" + name + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// defaultPreset holds the default worldgen preset, which is parsed once and shared by every test
+var defaultPreset struct {
+	once   sync.Once
+	preset *WorldgenPreset
+	err    error
+}
+
+// testWorldgen creates a world generator from the default preset
+func testWorldgen(tb testing.TB, seed, size int) *WorldGenerator {
+	tb.Helper()
+	defaultPreset.once.Do(func() {
+		data, err := ioutil.ReadFile("../assets/worldgen/default.json")
+		if err != nil {
+			defaultPreset.err = err
+			return
+		}
+		defaultPreset.preset, defaultPreset.err = ParseWorldgenPreset("default", data)
+	})
+	if defaultPreset.err != nil {
+		tb.Fatal(defaultPreset.err)
+	}
+	wg, err := defaultPreset.preset.Generator(seed, size)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return wg
+}
+
+func TestDefaultPresetVoxels(t *testing.T) {
+	wg := testWorldgen(t, 31481234, 16)
+	for _, cp := range []ChunkPos{{0, 0, 0}, {-3, 0, 5}} {
+		chunk := wg.Chunk(cp.X, cp.Y, cp.Z)
+		for z := 0; z < chunk.Sz; z++ {
+			for y := 0; y < chunk.Sy; y++ {
+				for x := 0; x < chunk.Sx; x++ {
+					if chunk.At(x, y, z) != wg.Voxel(chunk.Ox+x, chunk.Oy+y, chunk.Oz+z) {
+						t.Fatalf("expected chunk %v to match the generator at %d,%d,%d", cp, x, y, z)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPresetValidation(t *testing.T) {
+	broken := strings.NewReplacer(
+		`"Scale": 96`, `"Scale": 0`,
+		`"Type": "warp"`, `"Type": "wrap"`,
+		`"Block": "Ice"`, `"Block": "Slush"`,
+		`"Surface": "snow"`, `"Surface": "snw"`,
+		`"Structure": "oak", "Chance": 0.7`, `"Structure": "oak", "Chance": 0.97`,
+		`"Name": "Desert", "Temperature": 0.8`, `"Name": "Desert", "Temperature": 1.8`,
+	).Replace(string(loadTestPreset(t, "default")))
+
+	_, err := ParseWorldgenPreset("broken", []byte(broken))
+	perr, ok := err.(*PresetError)
+	if !ok {
+		t.Fatalf("expected a preset error, got %v", err)
+	}
+	expected := []string{
+		`Ridges.Source.Source.Scale: must be positive, was 0`,
+		`Cave.Type: unknown noise type "wrap"`,
+		`Palette.ice.Block: unknown block "Slush"`,
+		`Biomes[4].Surface: unknown palette entry "snw"`,
+		`Biomes[1].Structures: chances add up to 1.02, which is more than 1`,
+		`Biomes[2].Temperature: must be in the range [-1, 1], was 1.8`,
+	}
+	for _, problem := range expected {
+		found := false
+		for _, p := range perr.Problems {
+			found = found || p == problem
+		}
+		if !found {
+			t.Errorf("expected problem %q, got %v", problem, perr.Problems)
+		}
+	}
+	if len(perr.Problems) != len(expected) {
+		t.Errorf("expected %d problems, got %d: %v", len(expected), len(perr.Problems), perr.Problems)
+	}
+
+	if _, err := ParseWorldgenPreset("typo", []byte(`{"Temprature": {}}`)); err == nil || !strings.Contains(err.Error(), "Temprature") {
+		t.Errorf("expected unknown fields to be rejected, got %v", err)
+	}
+}
+
+func TestNewWorldPreset(t *testing.T) {
+	dir := WorldgenPresetDir
+	WorldgenPresetDir = "../assets/worldgen"
+	defer func() { WorldgenPresetDir = dir }()
+
+	world, err := NewWorld("default", 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if world.BiomeAt(0, 0) == nil {
+		t.Error("expected the world to be generated by the preset")
+	}
+	if _, err := NewWorld("missing", 1, 4); err == nil {
+		t.Error("expected an error for a missing preset")
+	}
+}
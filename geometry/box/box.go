@@ -4,6 +4,7 @@ import (
 	"github.com/johanhenriksson/goworld/engine"
 	"github.com/johanhenriksson/goworld/engine/object"
 	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/physics"
 	"github.com/johanhenriksson/goworld/render"
 	"github.com/johanhenriksson/goworld/render/vertex"
 )
@@ -80,4 +81,8 @@ func (b *T) compute() {
 		{P: vec3.New(x+w, y+h, z+d), C: c},
 	}
 	b.Buffer(vertices)
+	b.SetBounds(physics.Box{
+		Extents: b.Size,
+		Center:  b.Size.Scaled(0.5),
+	})
 }
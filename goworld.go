@@ -19,6 +19,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/johanhenriksson/goworld/editor"
 	"github.com/johanhenriksson/goworld/engine"
@@ -57,25 +58,45 @@ func main() {
 	}
 
 	// create chunk
-	world := game.NewWorld(31481234, 16)
-	chunk := world.AddChunk(0, 0)
+	world, err := game.NewWorld("default", 31481234, 16)
+	if err != nil {
+		fmt.Println("Error creating world:", err)
+		os.Exit(1)
+	}
 
-	// first person controls
-	player := game.NewPlayer(camera, func(player *game.Player, target vec3.T) (bool, vec3.T) {
-		height := world.HeightAt(target)
-		if target.Y < height {
-			return true, vec3.New(target.X, height, target.Z)
+	// convert chunk files from older versions into region files
+	if _, err := os.Stat("regions"); os.IsNotExist(err) {
+		if converted, err := game.ConvertChunkDir("chunks", world.Store); err != nil {
+			fmt.Println("Error converting chunk files:", err)
+		} else if converted > 0 {
+			fmt.Println("Converted", converted, "chunk files to regions")
 		}
-		return false, vec3.Zero
-	})
+	}
+
+	chunk, err := world.AddChunk(0, 0, 0)
+	if err != nil {
+		fmt.Println("Error loading chunk:", err)
+		os.Exit(1)
+	}
+
+	// stream chunks around the player
+	streamer := game.NewChunkStreamer(world, 4)
+	scene.Attach(streamer)
+
+	// first person controls
+	player := game.NewPlayer(camera, world.Solid)
 	player.Flying = true
+	player.Surface = func(feet vec3.T) *game.BlockType {
+		return world.BlockAt(feet.Sub(vec3.New(0, 0.5, 0)))
+	}
 
 	// create editor
-	edit := editor.NewEditor(chunk, camera, app.Pipeline.Geometry.Buffer)
+	edit := editor.NewEditor(world, chunk, camera)
 	scene.Attach(edit)
 
 	// buffer debug windows
 	uim.Attach(editor.DebugBufferWindows(app))
+	uim.Attach(editor.DebugCullWindow(app))
 
 	// gizmo := geometry.NewGizmo(vec3.New(-1, 0, -1))
 	// scene.Attach(gizmo)
@@ -101,8 +122,20 @@ func main() {
 
 		// movement etc
 		player.Update(dt)
+		streamer.SetFocus(camera.Position())
+
+		// simulate fluids & autosave
+		if err := world.Update(dt); err != nil {
+			fmt.Println("Error saving world:", err)
+		}
 	}
 
 	fmt.Println("Ok")
 	app.Run()
+
+	// write any unsaved changes before exiting
+	streamer.Stop()
+	if err := world.Flush(); err != nil {
+		fmt.Println("Error saving world:", err)
+	}
 }
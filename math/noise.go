@@ -4,6 +4,15 @@ import (
 	opensimplex "github.com/ojrac/opensimplex-go"
 )
 
+// NoiseSource is a continuous function of space, which can be sampled in two, three or four
+// dimensions. Unless stated otherwise, sources return values in the range [-1, 1].
+// Sources are composed into noise graphs using fractals, domain warping and combinators.
+type NoiseSource interface {
+	Sample2(x, y float32) float32
+	Sample3(x, y, z float32) float32
+	Sample4(x, y, z, w float32) float32
+}
+
 // Noise utility to sample simplex noise
 type Noise struct {
 	opensimplex.Noise
@@ -22,7 +31,20 @@ func NewNoise(seed int, freq float32) *Noise {
 
 // Sample the noise at a certain position
 func (n *Noise) Sample(x, y, z int) float32 {
-	// jeez
-	fx, fy, fz := float64(float32(x)*n.Freq), float64(float32(y)*n.Freq), float64(float32(z)*n.Freq)
-	return float32(n.Eval3(fx, fy, fz))
+	return n.Sample3(float32(x), float32(y), float32(z))
+}
+
+// Sample2 samples two dimensional noise
+func (n *Noise) Sample2(x, y float32) float32 {
+	return float32(n.Eval2(float64(x*n.Freq), float64(y*n.Freq)))
+}
+
+// Sample3 samples three dimensional noise
+func (n *Noise) Sample3(x, y, z float32) float32 {
+	return float32(n.Eval3(float64(x*n.Freq), float64(y*n.Freq), float64(z*n.Freq)))
+}
+
+// Sample4 samples four dimensional noise
+func (n *Noise) Sample4(x, y, z, w float32) float32 {
+	return float32(n.Eval4(float64(x*n.Freq), float64(y*n.Freq), float64(z*n.Freq), float64(w*n.Freq)))
 }
@@ -0,0 +1,151 @@
+package math
+
+// FractalMode selects how the octaves of a fractal are shaped before they are summed
+type FractalMode int
+
+const (
+	// FractalFBM sums the octaves as they are, producing fractional brownian motion
+	FractalFBM FractalMode = iota
+
+	// FractalRidged folds each octave into sharp ridges, useful for mountain ranges
+	FractalRidged
+
+	// FractalBillow folds each octave into rounded bumps, useful for hills and clouds
+	FractalBillow
+)
+
+// fractalOffset shifts each octave, so that octaves sampled from the same source are uncorrelated
+const fractalOffset = 71.3
+
+// Fractal layers several octaves of a noise source. Each octave is sampled at Lacunarity times
+// the frequency, and weighted by Gain times the amplitude of the previous octave.
+// The sum is normalized to the range [-1, 1].
+type Fractal struct {
+	Source     NoiseSource
+	Mode       FractalMode
+	Octaves    int
+	Lacunarity float32
+	Gain       float32
+}
+
+// NewFBM creates fractional brownian motion from a noise source
+func NewFBM(source NoiseSource, octaves int, lacunarity, gain float32) *Fractal {
+	return &Fractal{
+		Source:     source,
+		Mode:       FractalFBM,
+		Octaves:    octaves,
+		Lacunarity: lacunarity,
+		Gain:       gain,
+	}
+}
+
+// NewRidged creates ridged fractal noise from a noise source
+func NewRidged(source NoiseSource, octaves int, lacunarity, gain float32) *Fractal {
+	f := NewFBM(source, octaves, lacunarity, gain)
+	f.Mode = FractalRidged
+	return f
+}
+
+// NewBillow creates billowing fractal noise from a noise source
+func NewBillow(source NoiseSource, octaves int, lacunarity, gain float32) *Fractal {
+	f := NewFBM(source, octaves, lacunarity, gain)
+	f.Mode = FractalBillow
+	return f
+}
+
+// shape transforms a single octave according to the fractal mode
+func (f *Fractal) shape(v float32) float32 {
+	switch f.Mode {
+	case FractalRidged:
+		r := 1 - Abs(v)
+		return 2*r*r - 1
+	case FractalBillow:
+		return 2*Abs(v) - 1
+	default:
+		return v
+	}
+}
+
+// sum adds up the octaves returned by sample, which is passed the octave frequency and offset
+func (f *Fractal) sum(sample func(freq, offset float32) float32) float32 {
+	total, amplitude, weight := float32(0), float32(1), float32(0)
+	freq := float32(1)
+	for i := 0; i < f.Octaves; i++ {
+		total += amplitude * f.shape(sample(freq, float32(i)*fractalOffset))
+		weight += amplitude
+		amplitude *= f.Gain
+		freq *= f.Lacunarity
+	}
+	if weight == 0 {
+		return 0
+	}
+	return total / weight
+}
+
+// Sample2 samples two dimensional fractal noise
+func (f *Fractal) Sample2(x, y float32) float32 {
+	return f.sum(func(freq, o float32) float32 {
+		return f.Source.Sample2(x*freq+o, y*freq+o)
+	})
+}
+
+// Sample3 samples three dimensional fractal noise
+func (f *Fractal) Sample3(x, y, z float32) float32 {
+	return f.sum(func(freq, o float32) float32 {
+		return f.Source.Sample3(x*freq+o, y*freq+o, z*freq+o)
+	})
+}
+
+// Sample4 samples four dimensional fractal noise
+func (f *Fractal) Sample4(x, y, z, w float32) float32 {
+	return f.sum(func(freq, o float32) float32 {
+		return f.Source.Sample4(x*freq+o, y*freq+o, z*freq+o, w*freq+o)
+	})
+}
+
+// warpOffsets decorrelate the displacement along each axis of a domain warp
+var warpOffsets = [4]float32{0, 5.2, 1.3, 9.7}
+
+// Warp distorts the space a noise source is sampled in. The sample position is displaced
+// along each axis by Amount times the value of the Offset source.
+type Warp struct {
+	Source NoiseSource
+	Offset NoiseSource
+	Amount float32
+}
+
+// NewWarp creates a domain warp of a source, displaced by an offset source
+func NewWarp(source, offset NoiseSource, amount float32) *Warp {
+	return &Warp{
+		Source: source,
+		Offset: offset,
+		Amount: amount,
+	}
+}
+
+// Sample2 samples two dimensional warped noise
+func (d *Warp) Sample2(x, y float32) float32 {
+	o := warpOffsets
+	dx := d.Amount * d.Offset.Sample2(x+o[0], y+o[0])
+	dy := d.Amount * d.Offset.Sample2(x+o[1], y+o[1])
+	return d.Source.Sample2(x+dx, y+dy)
+}
+
+// Sample3 samples three dimensional warped noise
+func (d *Warp) Sample3(x, y, z float32) float32 {
+	o := warpOffsets
+	dx := d.Amount * d.Offset.Sample3(x+o[0], y+o[0], z+o[0])
+	dy := d.Amount * d.Offset.Sample3(x+o[1], y+o[1], z+o[1])
+	dz := d.Amount * d.Offset.Sample3(x+o[2], y+o[2], z+o[2])
+	return d.Source.Sample3(x+dx, y+dy, z+dz)
+}
+
+// Sample4 samples four dimensional warped noise
+func (d *Warp) Sample4(x, y, z, w float32) float32 {
+	o := warpOffsets
+	dx := d.Amount * d.Offset.Sample4(x+o[0], y+o[0], z+o[0], w+o[0])
+	dy := d.Amount * d.Offset.Sample4(x+o[1], y+o[1], z+o[1], w+o[1])
+	dz := d.Amount * d.Offset.Sample4(x+o[2], y+o[2], z+o[2], w+o[2])
+	dw := d.Amount * d.Offset.Sample4(x+o[3], y+o[3], z+o[3], w+o[3])
+	return d.Source.Sample4(x+dx, y+dy, z+dz, w+dw)
+}
@@ -0,0 +1,150 @@
+package math
+
+// Constant is a noise source with the same value everywhere
+type Constant float32
+
+// Sample2 returns the constant value
+func (c Constant) Sample2(x, y float32) float32 { return float32(c) }
+
+// Sample3 returns the constant value
+func (c Constant) Sample3(x, y, z float32) float32 { return float32(c) }
+
+// Sample4 returns the constant value
+func (c Constant) Sample4(x, y, z, w float32) float32 { return float32(c) }
+
+// noiseMap applies a function to the values of a noise source
+type noiseMap struct {
+	source NoiseSource
+	fn     func(v float32) float32
+}
+
+func (m *noiseMap) Sample2(x, y float32) float32 {
+	return m.fn(m.source.Sample2(x, y))
+}
+
+func (m *noiseMap) Sample3(x, y, z float32) float32 {
+	return m.fn(m.source.Sample3(x, y, z))
+}
+
+func (m *noiseMap) Sample4(x, y, z, w float32) float32 {
+	return m.fn(m.source.Sample4(x, y, z, w))
+}
+
+// noiseCombine combines the values of two noise sources
+type noiseCombine struct {
+	a, b NoiseSource
+	fn   func(a, b float32) float32
+}
+
+func (c *noiseCombine) Sample2(x, y float32) float32 {
+	return c.fn(c.a.Sample2(x, y), c.b.Sample2(x, y))
+}
+
+func (c *noiseCombine) Sample3(x, y, z float32) float32 {
+	return c.fn(c.a.Sample3(x, y, z), c.b.Sample3(x, y, z))
+}
+
+func (c *noiseCombine) Sample4(x, y, z, w float32) float32 {
+	return c.fn(c.a.Sample4(x, y, z, w), c.b.Sample4(x, y, z, w))
+}
+
+// fold combines any number of sources pairwise, from left to right
+func fold(sources []NoiseSource, fn func(a, b float32) float32) NoiseSource {
+	if len(sources) == 0 {
+		panic("noise combinator requires at least one source")
+	}
+	result := sources[0]
+	for _, source := range sources[1:] {
+		result = &noiseCombine{a: result, b: source, fn: fn}
+	}
+	return result
+}
+
+// Add returns a noise source summing the values of the given sources
+func Add(sources ...NoiseSource) NoiseSource {
+	return fold(sources, func(a, b float32) float32 { return a + b })
+}
+
+// Multiply returns a noise source multiplying the values of the given sources
+func Multiply(sources ...NoiseSource) NoiseSource {
+	return fold(sources, func(a, b float32) float32 { return a * b })
+}
+
+// ClampNoise returns a noise source limiting the values of a source to the range [min, max]
+func ClampNoise(source NoiseSource, min, max float32) NoiseSource {
+	return &noiseMap{source: source, fn: func(v float32) float32 {
+		return Clamp(v, min, max)
+	}}
+}
+
+// Remap returns a noise source linearly mapping values of a source from the range [fromMin, fromMax]
+// to the range [toMin, toMax]. Values outside the source range are extrapolated.
+func Remap(source NoiseSource, fromMin, fromMax, toMin, toMax float32) NoiseSource {
+	scale := (toMax - toMin) / (fromMax - fromMin)
+	return &noiseMap{source: source, fn: func(v float32) float32 {
+		return toMin + (v-fromMin)*scale
+	}}
+}
+
+// selectNoise picks between two sources depending on the value of a control source
+type selectNoise struct {
+	control   NoiseSource
+	low, high NoiseSource
+	threshold float32
+	falloff   float32
+}
+
+// Select returns a noise source taking its values from low where the control source is below the
+// threshold, and from high where it is above. Within falloff of the threshold, the two sources
+// are blended smoothly.
+func Select(control, low, high NoiseSource, threshold, falloff float32) NoiseSource {
+	return &selectNoise{
+		control:   control,
+		low:       low,
+		high:      high,
+		threshold: threshold,
+		falloff:   falloff,
+	}
+}
+
+// blend returns the weight of the high source for a given control value
+func (s *selectNoise) blend(c float32) float32 {
+	if s.falloff <= 0 {
+		if c < s.threshold {
+			return 0
+		}
+		return 1
+	}
+	t := Clamp((c-s.threshold+s.falloff)/(2*s.falloff), 0, 1)
+	return t * t * (3 - 2*t)
+}
+
+// pick evaluates only the sources required for the given blend weight
+func (s *selectNoise) pick(t float32, low, high func() float32) float32 {
+	switch t {
+	case 0:
+		return low()
+	case 1:
+		return high()
+	default:
+		return Lerp(low(), high(), t)
+	}
+}
+
+func (s *selectNoise) Sample2(x, y float32) float32 {
+	return s.pick(s.blend(s.control.Sample2(x, y)),
+		func() float32 { return s.low.Sample2(x, y) },
+		func() float32 { return s.high.Sample2(x, y) })
+}
+
+func (s *selectNoise) Sample3(x, y, z float32) float32 {
+	return s.pick(s.blend(s.control.Sample3(x, y, z)),
+		func() float32 { return s.low.Sample3(x, y, z) },
+		func() float32 { return s.high.Sample3(x, y, z) })
+}
+
+func (s *selectNoise) Sample4(x, y, z, w float32) float32 {
+	return s.pick(s.blend(s.control.Sample4(x, y, z, w)),
+		func() float32 { return s.low.Sample4(x, y, z, w) },
+		func() float32 { return s.high.Sample4(x, y, z, w) })
+}
@@ -0,0 +1,72 @@
+package math
+
+import (
+	"testing"
+)
+
+func TestNoiseSampleMatchesFloat(t *testing.T) {
+	noise := NewNoise(1, 1.0/16)
+	for i := -20; i < 20; i++ {
+		if a, b := noise.Sample(i, 2*i, -i), noise.Sample3(float32(i), float32(2*i), float32(-i)); a != b {
+			t.Errorf("expected integer and float samples to match, %f != %f", a, b)
+		}
+	}
+}
+
+func TestFractalRange(t *testing.T) {
+	noise := NewNoise(7, 1.0/8)
+	fractals := map[string]NoiseSource{
+		"fbm":    NewFBM(noise, 5, 2, 0.5),
+		"ridged": NewRidged(noise, 5, 2, 0.5),
+		"billow": NewBillow(noise, 5, 2, 0.5),
+		"warp":   NewWarp(noise, NewFBM(noise, 2, 2, 0.5), 4),
+	}
+	for name, source := range fractals {
+		min, max := float32(1), float32(-1)
+		for i := 0; i < 2000; i++ {
+			x, y, z := float32(i%40)*1.3, float32(i/40)*0.7, float32(i%13)
+			for _, v := range []float32{source.Sample2(x, y), source.Sample3(x, y, z), source.Sample4(x, y, z, x-y)} {
+				if v < -1.001 || v > 1.001 {
+					t.Fatalf("%s: sample %f out of range", name, v)
+				}
+				min, max = Min(min, v), Max(max, v)
+			}
+		}
+		if max-min < 0.5 {
+			t.Errorf("%s: expected varying samples, range was [%f, %f]", name, min, max)
+		}
+	}
+}
+
+func TestFractalSingleOctave(t *testing.T) {
+	noise := NewNoise(3, 1.0/8)
+	fbm := NewFBM(noise, 1, 2, 0.5)
+	if a, b := fbm.Sample3(1, 2, 3), noise.Sample3(1, 2, 3); a != b {
+		t.Errorf("expected a single octave of fbm to equal its source, %f != %f", a, b)
+	}
+}
+
+func TestNoiseCombinators(t *testing.T) {
+	half := Constant(0.5)
+	cases := []struct {
+		Name     string
+		Source   NoiseSource
+		Expected float32
+	}{
+		{"add", Add(half, half, Constant(1)), 2},
+		{"multiply", Multiply(half, Constant(4)), 2},
+		{"clamp", ClampNoise(Constant(3), -1, 1), 1},
+		{"remap", Remap(half, -1, 1, 0, 100), 75},
+		{"select low", Select(Constant(-1), Constant(1), Constant(2), 0, 0.5), 1},
+		{"select high", Select(Constant(1), Constant(1), Constant(2), 0, 0.5), 2},
+		{"select blend", Select(Constant(0), Constant(1), Constant(2), 0, 0.5), 1.5},
+	}
+	for _, c := range cases {
+		values := []float32{c.Source.Sample2(1, 2), c.Source.Sample3(1, 2, 3), c.Source.Sample4(1, 2, 3, 4)}
+		for _, v := range values {
+			if !EqualThreshold(v, c.Expected, 1e-6) {
+				t.Errorf("%s: expected %f, got %f", c.Name, c.Expected, v)
+			}
+		}
+	}
+}
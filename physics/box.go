@@ -1,6 +1,8 @@
 package physics
 
 import (
+	"github.com/johanhenriksson/goworld/math"
+	"github.com/johanhenriksson/goworld/math/mat4"
 	"github.com/johanhenriksson/goworld/math/vec3"
 )
 
@@ -28,6 +30,22 @@ func (box Box) Max() vec3.T {
 	}
 }
 
+// Transform returns the axis-aligned box enclosing this box after applying an affine transform.
+func (box Box) Transform(m mat4.T) Box {
+	// Transforming Axis-Aligned Bounding Boxes by James Arvo
+	// from "Graphics Gems", Academic Press, 1990
+	e := [3]float32{}
+	for row := range e {
+		e[row] = math.Abs(m.At(row, 0))*box.Extents.X +
+			math.Abs(m.At(row, 1))*box.Extents.Y +
+			math.Abs(m.At(row, 2))*box.Extents.Z
+	}
+	return Box{
+		Extents: vec3.New(e[0], e[1], e[2]),
+		Center:  m.TransformPoint(box.Center),
+	}
+}
+
 // Intersect a ray with this box. Returns a value indicating if it hit, and if so, the point of intersection.
 func (box *Box) Intersect(ray *Ray) (bool, vec3.T) {
 	// Fast Ray-Box Intersection by Andrew Woo
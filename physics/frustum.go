@@ -0,0 +1,67 @@
+package physics
+
+import (
+	"github.com/johanhenriksson/goworld/math"
+	"github.com/johanhenriksson/goworld/math/mat4"
+	"github.com/johanhenriksson/goworld/math/vec3"
+	"github.com/johanhenriksson/goworld/math/vec4"
+)
+
+// Frustum is the volume visible through a camera, bounded by six planes with normals pointing inwards.
+type Frustum struct {
+	// Left, Right, Bottom, Top, Near, Far
+	Planes [6]Plane
+}
+
+// NewFrustum extracts the frustum planes from a view-projection matrix.
+// Planes are given in the space the matrix transforms from, usually world space.
+func NewFrustum(vp mat4.T) Frustum {
+	// Fast Extraction of Viewing Frustum Planes from the World-View-Projection Matrix
+	// by Gil Gribb & Klaus Hartmann
+	r0, r1, r2, r3 := vp.Rows()
+	return Frustum{
+		Planes: [6]Plane{
+			planeFrom(r3.Add(r0)),
+			planeFrom(r3.Sub(r0)),
+			planeFrom(r3.Add(r1)),
+			planeFrom(r3.Sub(r1)),
+			planeFrom(r3.Add(r2)),
+			planeFrom(r3.Sub(r2)),
+		},
+	}
+}
+
+// planeFrom creates a normalized plane from its equation coefficients
+func planeFrom(v vec4.T) Plane {
+	normal := v.XYZ()
+	length := normal.Length()
+	return Plane{
+		Normal: normal.Scaled(1 / length),
+		D:      v.W / length,
+	}
+}
+
+// IntersectsBox returns true if any part of the box may be inside the frustum.
+// Boxes close to the corners of the frustum can be reported as intersecting even though they
+// are outside, but boxes that are at least partially inside are never rejected.
+func (f *Frustum) IntersectsBox(box Box) bool {
+	half := box.Extents.Scaled(0.5)
+	for _, plane := range f.Planes {
+		// projected radius of the box onto the plane normal
+		radius := half.X*math.Abs(plane.Normal.X) + half.Y*math.Abs(plane.Normal.Y) + half.Z*math.Abs(plane.Normal.Z)
+		if vec3.Dot(plane.Normal, box.Center)+plane.D < -radius {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsPoint returns true if the point is inside the frustum
+func (f *Frustum) IntersectsPoint(point vec3.T) bool {
+	for _, plane := range f.Planes {
+		if vec3.Dot(plane.Normal, point)+plane.D < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package physics
+
+import (
+	"testing"
+
+	"github.com/johanhenriksson/goworld/math"
+	"github.com/johanhenriksson/goworld/math/mat4"
+	"github.com/johanhenriksson/goworld/math/vec3"
+)
+
+// testFrustum looks down the negative z axis from the origin, with a 90 degree field of view
+func testFrustum() Frustum {
+	proj := mat4.Perspective(math.DegToRad(90), 1, 1, 100)
+	view := mat4.LookAt(vec3.Zero, vec3.New(0, 0, -1))
+	return NewFrustum(proj.Mul(&view))
+}
+
+func TestFrustumPoints(t *testing.T) {
+	frustum := testFrustum()
+	cases := []struct {
+		point  vec3.T
+		inside bool
+	}{
+		{vec3.New(0, 0, -10), true},
+		{vec3.New(9, -9, -10), true},
+		{vec3.New(11, 0, -10), false},
+		{vec3.New(0, 11, -10), false},
+		{vec3.New(0, 0, 10), false},
+		{vec3.New(0, 0, -0.5), false},
+		{vec3.New(0, 0, -99), true},
+		{vec3.New(0, 0, -101), false},
+	}
+	for _, c := range cases {
+		if inside := frustum.IntersectsPoint(c.point); inside != c.inside {
+			t.Errorf("expected point %v inside=%t, was %t", c.point, c.inside, inside)
+		}
+	}
+}
+
+func TestFrustumBoxes(t *testing.T) {
+	frustum := testFrustum()
+	cases := []struct {
+		name    string
+		box     Box
+		visible bool
+	}{
+		{"in front", Box{Extents: vec3.One, Center: vec3.New(0, 0, -10)}, true},
+		{"behind", Box{Extents: vec3.One, Center: vec3.New(0, 0, 10)}, false},
+		{"beyond far plane", Box{Extents: vec3.One, Center: vec3.New(0, 0, -110)}, false},
+		{"left of frustum", Box{Extents: vec3.One, Center: vec3.New(-20, 0, -10)}, false},
+		{"above frustum", Box{Extents: vec3.One, Center: vec3.New(0, 20, -10)}, false},
+		{"straddling left plane", Box{Extents: vec3.New(4, 1, 1), Center: vec3.New(-11, 0, -10)}, true},
+		{"straddling near plane", Box{Extents: vec3.New(1, 1, 4), Center: vec3.New(0, 0, 0)}, true},
+		{"enclosing frustum", Box{Extents: vec3.New(1000, 1000, 1000), Center: vec3.Zero}, true},
+	}
+	for _, c := range cases {
+		if visible := frustum.IntersectsBox(c.box); visible != c.visible {
+			t.Errorf("expected box %s visible=%t, was %t", c.name, c.visible, visible)
+		}
+	}
+}
+
+func TestBoxTransform(t *testing.T) {
+	box := Box{Extents: vec3.New(2, 4, 6), Center: vec3.New(1, 2, 3)}
+
+	moved := box.Transform(mat4.Translate(vec3.New(10, 0, -10)))
+	if moved.Center != vec3.New(11, 2, -7) || moved.Extents != box.Extents {
+		t.Errorf("expected translated box at (11,2,-7) of size (2,4,6), was %+v", moved)
+	}
+
+	scaled := box.Transform(mat4.Scale(vec3.New(2, 2, 2)))
+	if scaled.Center != vec3.New(2, 4, 6) || scaled.Extents != vec3.New(4, 8, 12) {
+		t.Errorf("expected scaled box at (2,4,6) of size (4,8,12), was %+v", scaled)
+	}
+
+	// a quarter turn around the y axis swaps the x and z extents
+	rotated := box.Transform(mat4.Rotate(vec3.New(0, 90, 0)))
+	if !approxEqual(rotated.Extents, vec3.New(6, 4, 2)) {
+		t.Errorf("expected rotated box of size (6,4,2), was %v", rotated.Extents)
+	}
+	if !approxEqual(rotated.Center, vec3.New(3, 2, -1)) {
+		t.Errorf("expected rotated box at (3,2,-1), was %v", rotated.Center)
+	}
+}
+
+func approxEqual(a, b vec3.T) bool {
+	const epsilon = 1e-4
+	d := a.Sub(b)
+	return math.Abs(d.X) < epsilon && math.Abs(d.Y) < epsilon && math.Abs(d.Z) < epsilon
+}